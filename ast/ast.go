@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"strings"
 
 	"github.com/kiki-ki/go-monkey/token"
@@ -24,6 +25,12 @@ type Expression interface {
 	expressionNode()
 }
 
+// 分割代入などで値の形にマッチさせるパターン
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 // ルートノード
 type Program struct {
 	Statements []Statement
@@ -46,9 +53,10 @@ func (p *Program) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token // token.LET
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // token.LET
+	Name    *Identifier
+	Pattern Pattern // 分割代入の場合のみ. Nameはnil
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -60,7 +68,11 @@ func (ls *LetStatement) TokenLiteral() string {
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -69,6 +81,139 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// 再代入できない束縛 ex: const x = 5;
+type ConstStatement struct {
+	Token token.Token // token.CONST
+	Name  *Identifier
+	Value Expression
+}
+
+func (cs *ConstStatement) statementNode() {}
+
+func (cs *ConstStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ConstStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	out.WriteString(" = ")
+	if cs.Value != nil {
+		out.WriteString(cs.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// struct Point { x, y, fn norm(self) { self.x * self.x + self.y * self.y } }
+type StructStatement struct {
+	Token   token.Token // token.STRUCT
+	Name    *Identifier
+	Fields  []*Identifier
+	Methods []*StructMethod
+}
+
+// メソッドは最初の引数でレシーバを受け取る
+type StructMethod struct {
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (sm *StructMethod) String() string {
+	return sm.Function.TokenLiteral() + " " + sm.Name.String() + sm.Function.signature()
+}
+
+func (ss *StructStatement) statementNode() {}
+
+func (ss *StructStatement) TokenLiteral() string {
+	return ss.Token.Literal
+}
+
+func (ss *StructStatement) String() string {
+	fields := make([]string, 0, len(ss.Fields)+len(ss.Methods))
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+	for _, m := range ss.Methods {
+		fields = append(fields, m.String())
+	}
+	return ss.TokenLiteral() + " " + ss.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// enum Shape { Circle(r), Rect(w, h), Empty }
+// 各バリアントは同名のコンストラクタになる
+type EnumStatement struct {
+	Token    token.Token // token.ENUM
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+	fields := make([]string, 0, len(ev.Fields))
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+func (es *EnumStatement) statementNode() {}
+
+func (es *EnumStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+
+func (es *EnumStatement) String() string {
+	variants := make([]string, 0, len(es.Variants))
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+	return es.TokenLiteral() + " " + es.Name.String() + " { " + strings.Join(variants, ", ") + " }"
+}
+
+// import "lib/strings.mk" as s;
+type ImportStatement struct {
+	Token token.Token // token.IMPORT
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + is.Path.String() + " as " + is.Alias.String() + ";"
+}
+
+// export let f = fn(x) { x };
+// let, const, struct, enumを公開する
+type ExportStatement struct {
+	Token     token.Token // token.EXPORT
+	Statement Statement
+	Names     []*Identifier // 公開される名前
+}
+
+func (es *ExportStatement) statementNode() {}
+
+func (es *ExportStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
 type ReturnStatement struct {
 	Token       token.Token // token.RETURN
 	ReturnValue Expression
@@ -87,7 +232,7 @@ func (rs *ReturnStatement) String() string {
 		out.WriteString(rs.ReturnValue.String())
 	}
 	out.WriteString(";")
-	return ""
+	return out.String()
 }
 
 type BlockStatement struct {
@@ -109,6 +254,155 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+// throw "message";
+type ThrowStatement struct {
+	Token token.Token // throw
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// try { ... } catch (e) { ... } finally { ... }
+// catch, finallyのどちらかは省略できる
+type TryStatement struct {
+	Token   token.Token // try
+	Block   *BlockStatement
+	Param   *Identifier // catchで捕まえた例外を束縛する名前
+	Catch   *BlockStatement
+	Finally *BlockStatement
+}
+
+func (ts *TryStatement) statementNode() {}
+
+func (ts *TryStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(ts.Block.String())
+	if ts.Catch != nil {
+		out.WriteString(" catch(" + ts.Param.String() + ") ")
+		out.WriteString(ts.Catch.String())
+	}
+	if ts.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(ts.Finally.String())
+	}
+	return out.String()
+}
+
+// defer close(f);
+// 関数を抜けるときに後に登録したものから順に評価する
+type DeferStatement struct {
+	Token token.Token // defer
+	Value Expression
+}
+
+func (ds *DeferStatement) statementNode() {}
+
+func (ds *DeferStatement) TokenLiteral() string {
+	return ds.Token.Literal
+}
+
+func (ds *DeferStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(ds.TokenLiteral() + " ")
+	if ds.Value != nil {
+		out.WriteString(ds.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+type WhileStatement struct {
+	Token     token.Token // while
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ws.Body.String())
+	return out.String()
+}
+
+type ForStatement struct {
+	Token    token.Token // for
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("for(")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+	return out.String()
+}
+
+type BreakStatement struct {
+	Token token.Token // break
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+type ContinueStatement struct {
+	Token token.Token // continue
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
 type ExpressionStatement struct {
 	Token      token.Token // 式の最初のトークン
 	Expression Expression
@@ -133,6 +427,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode() {}
+func (i *Identifier) patternNode()    {}
 
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
@@ -157,6 +452,52 @@ func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+// int64に収まらない整数リテラル
+type BigIntLiteral struct {
+	Token token.Token // token.INT
+	Value *big.Int
+}
+
+func (bl *BigIntLiteral) expressionNode() {}
+
+func (bl *BigIntLiteral) TokenLiteral() string {
+	return bl.Token.Literal
+}
+
+func (bl *BigIntLiteral) String() string {
+	return bl.Token.Literal
+}
+
+type FloatLiteral struct {
+	Token token.Token // token.FLOAT
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
+type StringLiteral struct {
+	Token token.Token // token.STRING
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+
+func (sl *StringLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl *StringLiteral) String() string {
+	return `"` + sl.Value + `"`
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -216,6 +557,29 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+type AssignExpression struct {
+	Token    token.Token // =, +=, -=, *=, /=
+	Target   Expression  // *Identifier
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 type IfExpression struct {
 	Token       token.Token // if
 	Condition   Expression
@@ -242,9 +606,36 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+type ConditionalExpression struct {
+	Token       token.Token // ?
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+
+func (ce *ConditionalExpression) TokenLiteral() string {
+	return ce.Token.Literal
+}
+
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 type FunctionLiteral struct {
 	Token      token.Token // fn
 	Parameters []*Identifier
+	Defaults   map[string]Expression // 引数名 => デフォルト値
+	Rest       *Identifier           // ...rest
 	Body       *BlockStatement
 }
 
@@ -255,12 +646,23 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 }
 
 func (fl *FunctionLiteral) String() string {
+	return fl.TokenLiteral() + fl.signature()
+}
+
+// 引数リストと本体 ex: (x, y = 1) { x + y }
+func (fl *FunctionLiteral) signature() string {
 	var out bytes.Buffer
 	params := make([]string, 0)
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		if d, ok := fl.Defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+d.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
-	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -269,6 +671,23 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// f(x, b: 3) のb: 3
+type KeywordArgument struct {
+	Token token.Token // token.IDENT
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode() {}
+
+func (ka *KeywordArgument) TokenLiteral() string {
+	return ka.Token.Literal
+}
+
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
 type CallExpression struct {
 	Token     token.Token // (
 	Function  Expression
@@ -287,10 +706,218 @@ func (ce *CallExpression) String() string {
 	for _, arg := range ce.Arguments {
 		args = append(args, arg.String())
 	}
-	out.WriteString(ce.Function.TokenLiteral())
+	out.WriteString(ce.Function.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 
 	return out.String()
 }
+
+// p.x
+type MemberExpression struct {
+	Token    token.Token // .
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+// Point{x: 1, y: 2}
+type StructLiteral struct {
+	Token  token.Token // {
+	Name   *Identifier
+	Fields []*StructLiteralField
+}
+
+type StructLiteralField struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (sl *StructLiteral) expressionNode() {}
+
+func (sl *StructLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl *StructLiteral) String() string {
+	fields := make([]string, 0, len(sl.Fields))
+	for _, f := range sl.Fields {
+		fields = append(fields, f.Name.String()+": "+f.Value.String())
+	}
+	return sl.Name.String() + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// [a, b, ...rest]
+type ArrayPattern struct {
+	Token    token.Token // [
+	Elements []Pattern
+	Rest     *Identifier
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+	elements := make([]string, 0)
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+// {name, age: years}
+type HashPattern struct {
+	Token token.Token // {
+	Pairs []*HashPatternPair
+}
+
+type HashPatternPair struct {
+	Key   *Identifier
+	Value Pattern // {name} のように省略した場合はKeyと同じ
+}
+
+func (hp *HashPattern) patternNode() {}
+
+func (hp *HashPattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+	pairs := make([]string, 0)
+	for _, pair := range hp.Pairs {
+		if pair.Value == Pattern(pair.Key) {
+			pairs = append(pairs, pair.Key.String())
+		} else {
+			pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+		}
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// 0, "a", true, -1 などのリテラルと等しい値にマッチする
+type LiteralPattern struct {
+	Token token.Token // リテラルの最初のトークン
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+func (lp *LiteralPattern) TokenLiteral() string {
+	return lp.Token.Literal
+}
+
+// パターンとして再び構文解析できるよう -1 は括弧で囲まない
+func (lp *LiteralPattern) String() string {
+	if pe, ok := lp.Value.(*PrefixExpression); ok {
+		return pe.Operator + pe.Right.String()
+	}
+	return lp.Value.String()
+}
+
+// Circle(r), Rect(w, _), Empty
+type VariantPattern struct {
+	Token  token.Token // バリアント名
+	Name   *Identifier
+	Fields []Pattern
+}
+
+func (vp *VariantPattern) patternNode() {}
+
+func (vp *VariantPattern) TokenLiteral() string {
+	return vp.Token.Literal
+}
+
+func (vp *VariantPattern) String() string {
+	if vp.Fields == nil {
+		return vp.Name.String()
+	}
+	fields := make([]string, 0, len(vp.Fields))
+	for _, f := range vp.Fields {
+		fields = append(fields, f.String())
+	}
+	return vp.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+// _ は何にでもマッチし, 何も束縛しない
+type WildcardPattern struct {
+	Token token.Token // _
+}
+
+func (wp *WildcardPattern) patternNode() {}
+
+func (wp *WildcardPattern) TokenLiteral() string {
+	return wp.Token.Literal
+}
+
+func (wp *WildcardPattern) String() string {
+	return "_"
+}
+
+type MatchExpression struct {
+	Token   token.Token // match
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// pattern if guard => body
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression
+	Body    Expression
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+	return out.String()
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+	arms := make([]string, 0)
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	out.WriteString("match(")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
@@ -1,25 +1,52 @@
 package lexer
 
-import "github.com/kiki-ki/go-monkey/token"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kiki-ki/go-monkey/token"
+)
 
 // 字句解析器
+// 入力はUTF-8として1文字(rune)ずつ読み進める
 
 type Lexer struct {
 	input        string
-	position     int  // 現在の位置
-	readPosition int  // 次の文字の位置
-	ch           byte // 現在検査してる文字
+	position     int  // 現在の位置(バイト)
+	readPosition int  // 次の文字の位置(バイト)
+	ch           rune // 現在検査してる文字
+	line         int  // chの行番号
+	column       int  // chの列番号(文字単位)
+	errors       []string
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+func (l *Lexer) addError(line, column int, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", line, column) + fmt.Sprintf(format, a...)
+	l.errors = append(l.errors, msg)
+}
+
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
 	l.skipWhiteSpace()
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case ';':
@@ -32,11 +59,36 @@ func (l *Lexer) NextToken() token.Token {
 		tok = token.New(token.LBRACE, l.ch)
 	case '}':
 		tok = token.New(token.RBRACE, l.ch)
+	case '[':
+		tok = token.New(token.LBRACKET, l.ch)
+	case ']':
+		tok = token.New(token.RBRACKET, l.ch)
 	case ',':
 		tok = token.New(token.COMMA, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = token.New(token.DOT, l.ch)
+		}
+	case '"':
+		tok.Type = token.STRING
+		tok.Literal = l.readString()
+	case ':':
+		tok = token.New(token.COLON, l.ch)
+	case '?':
+		if l.peekChar() == '?' {
+			tok = l.makeTwoCharToken(token.COALESCE)
+		} else {
+			tok = token.New(token.QUESTION, l.ch)
+		}
 	case '=':
 		if l.peekChar() == '=' {
 			tok = l.makeTwoCharToken(token.EQ)
+		} else if l.peekChar() == '>' {
+			tok = l.makeTwoCharToken(token.ARROW)
 		} else {
 			tok = token.New(token.ASSIGN, l.ch)
 		}
@@ -47,17 +99,57 @@ func (l *Lexer) NextToken() token.Token {
 			tok = token.New(token.BANG, l.ch)
 		}
 	case '+':
-		tok = token.New(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = token.New(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = token.New(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = token.New(token.MINUS, l.ch)
+		}
 	case '/':
-		tok = token.New(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = token.New(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = token.New(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.ASTERISK_ASSIGN)
+		} else {
+			tok = token.New(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tok = token.New(token.PERCENT, l.ch)
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.makeTwoCharToken(token.AND)
+		} else {
+			tok = token.New(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.makeTwoCharToken(token.OR)
+		} else if l.peekChar() == '>' {
+			tok = l.makeTwoCharToken(token.PIPE)
+		} else {
+			tok = token.New(token.ILLEGAL, l.ch)
+		}
 	case '<':
-		tok = token.New(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.LT_EQ)
+		} else {
+			tok = token.New(token.LT, l.ch)
+		}
 	case '>':
-		tok = token.New(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.makeTwoCharToken(token.GT_EQ)
+		} else {
+			tok = token.New(token.GT, l.ch)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -67,9 +159,10 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookUpIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
+		} else if l.ch == utf8.RuneError {
+			tok = token.Token{Type: token.ILLEGAL, Literal: l.input[l.position:l.readPosition]}
 		} else {
 			tok = token.New(token.ILLEGAL, l.ch)
 		}
@@ -79,20 +172,30 @@ func (l *Lexer) NextToken() token.Token {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
+		if l.ch == utf8.RuneError && width == 1 {
+			l.addError(l.line, l.column, "invalid UTF-8 encoding %q", l.input[l.readPosition:l.readPosition+width])
+		}
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return ch
 	}
 }
 
@@ -109,27 +212,159 @@ func (l *Lexer) skipWhiteSpace() {
 	}
 }
 
-func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+// "で囲まれた文字列を読み進め, 中身を返す
+func (l *Lexer) readString() string {
+	line, column := l.line, l.column
+	position := l.position + 1
+	for {
 		l.readChar()
+		if l.ch == '"' {
+			break
+		}
+		if l.ch == 0 {
+			l.addError(line, column, "unterminated string literal")
+			break
+		}
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isDigit(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-// 識別子,キーワードに利用可能な文字か判断
-func isLetter(ch byte) bool {
-	return 'a' <= ch && 'z' >= ch || 'A' <= ch && 'Z' >= ch || ch == '_'
+// 整数(10, 0xff, 0o17, 0b1010, 1_000)または浮動小数点数(1.5, 1e-3)を読み進める
+// 不正な数値リテラルはエラーを記録してILLEGALを返す
+func (l *Lexer) readNumber() (token.TokenType, string) {
+	position, line, column := l.position, l.line, l.column
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.readChar()
+		l.readChar()
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		literal := l.input[position:l.position]
+		if !l.validateBaseLiteral(literal, line, column) {
+			return token.ILLEGAL, literal
+		}
+		return token.INT, literal
+	}
+
+	var tt token.TokenType = token.INT
+	l.readDigits()
+	// 017のような先頭0の10進数は8進数と紛らわしいのでエラーにする
+	if intPart := l.input[position:l.position]; len(intPart) > 1 && intPart[0] == '0' {
+		for isDigit(l.ch) || l.ch == '.' || l.ch == 'e' || l.ch == 'E' {
+			l.readChar()
+		}
+		literal := l.input[position:l.position]
+		l.addError(line, column, "leading zero in decimal literal %q (use 0o prefix for octal)", literal)
+		return token.ILLEGAL, literal
+	}
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tt = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		tt = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		if !isDigit(l.ch) {
+			l.readDigits()
+			literal := l.input[position:l.position]
+			l.addError(line, column, "exponent has no digits in %q", literal)
+			return token.ILLEGAL, literal
+		}
+		l.readDigits()
+	}
+	literal := l.input[position:l.position]
+	if !l.validateSeparators(literal, isDigit, line, column) {
+		return token.ILLEGAL, literal
+	}
+	return tt, literal
+}
+
+// 桁区切りの'_'を含む数字の並びを読み進める
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+func (l *Lexer) validateBaseLiteral(literal string, line, column int) bool {
+	name, isBaseDigit := baseOf(rune(literal[1]))
+	digits := literal[2:]
+	hasDigit := false
+	for _, ch := range digits {
+		if ch == '_' {
+			continue
+		}
+		if !isBaseDigit(ch) {
+			l.addError(line, column, "invalid digit %q in %s literal %q", ch, name, literal)
+			return false
+		}
+		hasDigit = true
+	}
+	if !hasDigit {
+		l.addError(line, column, "%s literal %q has no digits", name, literal)
+		return false
+	}
+	return l.validateSeparators(literal, isBaseDigit, line, column)
+}
+
+// '_'が数字と数字の間(または基数接頭辞の直後 ex: 0x_ff)にのみ現れるか検査する
+func (l *Lexer) validateSeparators(literal string, isValidDigit func(rune) bool, line, column int) bool {
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '_' {
+			continue
+		}
+		afterPrefix := i == 2 && literal[0] == '0' && isBasePrefix(rune(literal[1]))
+		prevOK := i > 0 && (isValidDigit(rune(literal[i-1])) || afterPrefix)
+		nextOK := i+1 < len(literal) && isValidDigit(rune(literal[i+1]))
+		if !prevOK || !nextOK {
+			l.addError(line, column, "'_' must separate successive digits in %q", literal)
+			return false
+		}
+	}
+	return true
 }
 
-func isDigit(ch byte) bool {
+// 識別子,キーワードの先頭に利用可能な文字か判断
+// Unicodeの文字(L)カテゴリを受け付ける. 2文字目以降は数字(Nd)も利用可能
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+// 数値リテラルの数字はASCIIのみ
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// 基数接頭辞に対応する名前と数字の判定関数を返す
+func baseOf(prefix rune) (string, func(rune) bool) {
+	switch prefix {
+	case 'x', 'X':
+		return "hexadecimal", func(ch rune) bool {
+			return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+		}
+	case 'o', 'O':
+		return "octal", func(ch rune) bool { return '0' <= ch && ch <= '7' }
+	default:
+		return "binary", func(ch rune) bool { return ch == '0' || ch == '1' }
+	}
+}
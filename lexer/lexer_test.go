@@ -29,6 +29,23 @@ func TestNextToken(t *testing.T) {
 
 	10 == 10;
 	10 != 9;
+	a && b || c;
+	a <= b >= c % 2;
+	0.5 1e-3 2.5E+10 1_000.25 1_000;
+	0xff 0o17 0b1010 0X_FF_FF;
+	x += 1; x -= 1; x *= 2; x /= 2;
+	while for in break continue
+	a ? b : c ?? d;
+	xs |> f();
+	x => x;
+	fn(...rest) {};
+	[a];
+	"foo bar" "こんにちは" match
+	const
+	struct P { x }; p.x;
+	enum
+	import "lib/a.mk" as a; export
+	throw try catch finally defer
 	`
 
 	cases := []struct {
@@ -108,6 +125,108 @@ func TestNextToken(t *testing.T) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "0.5"},
+		{token.FLOAT, "1e-3"},
+		{token.FLOAT, "2.5E+10"},
+		{token.FLOAT, "1_000.25"},
+		{token.INT, "1_000"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "0xff"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "0X_FF_FF"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+		{token.COALESCE, "??"},
+		{token.IDENT, "d"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "xs"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "foo bar"},
+		{token.STRING, "こんにちは"},
+		{token.MATCH, "match"},
+		{token.CONST, "const"},
+		{token.STRUCT, "struct"},
+		{token.IDENT, "P"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "p"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.ENUM, "enum"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/a.mk"},
+		{token.AS, "as"},
+		{token.IDENT, "a"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.THROW, "throw"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.DEFER, "defer"},
 		{token.EOF, ""},
 	}
 
@@ -123,3 +242,147 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPosition(t *testing.T) {
+	in := "let x = 5;\n  x + 10;"
+
+	cases := []struct {
+		wantLiteral string
+		wantLine    int
+		wantColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"+", 2, 5},
+		{"10", 2, 7},
+		{";", 2, 9},
+		{"", 2, 10},
+	}
+
+	l := lexer.New(in)
+
+	for i, tt := range cases {
+		tok := l.NextToken()
+		if tok.Literal != tt.wantLiteral {
+			t.Fatalf("cases[%d]: token literal wrong, want=%q, got=%q", i, tt.wantLiteral, tok.Literal)
+		}
+		if tok.Line != tt.wantLine || tok.Column != tt.wantColumn {
+			t.Fatalf("cases[%d]: token position wrong, want=%d:%d, got=%d:%d", i, tt.wantLine, tt.wantColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestMalformedNumberLiterals(t *testing.T) {
+	cases := []struct {
+		input       string
+		wantLiteral string
+		wantError   string
+	}{
+		{"0x", "0x", `1:1: hexadecimal literal "0x" has no digits`},
+		{"0b_", "0b_", `1:1: binary literal "0b_" has no digits`},
+		{"0b12", "0b12", `1:1: invalid digit '2' in binary literal "0b12"`},
+		{"0o78", "0o78", `1:1: invalid digit '8' in octal literal "0o78"`},
+		{"0xfg", "0xfg", `1:1: invalid digit 'g' in hexadecimal literal "0xfg"`},
+		{"x = 1__000", "1__000", `1:5: '_' must separate successive digits in "1__000"`},
+		{"1_", "1_", `1:1: '_' must separate successive digits in "1_"`},
+		{"\n 1_.5", "1_.5", `2:2: '_' must separate successive digits in "1_.5"`},
+		{"0x_ff_", "0x_ff_", `1:1: '_' must separate successive digits in "0x_ff_"`},
+		{"1e+", "1e+", `1:1: exponent has no digits in "1e+"`},
+		{"09", "09", `1:1: leading zero in decimal literal "09" (use 0o prefix for octal)`},
+		{"x = 017", "017", `1:5: leading zero in decimal literal "017" (use 0o prefix for octal)`},
+		{"0_1", "0_1", `1:1: leading zero in decimal literal "0_1" (use 0o prefix for octal)`},
+		{"00.5", "00.5", `1:1: leading zero in decimal literal "00.5" (use 0o prefix for octal)`},
+	}
+
+	for i, tt := range cases {
+		l := lexer.New(tt.input)
+		var tok token.Token
+		for tok = l.NextToken(); tok.Type != token.ILLEGAL; tok = l.NextToken() {
+			if tok.Type == token.EOF {
+				t.Fatalf("cases[%d]: ILLEGAL token not found", i)
+			}
+		}
+		if tok.Literal != tt.wantLiteral {
+			t.Errorf("cases[%d]: token literal wrong, want=%q, got=%q", i, tt.wantLiteral, tok.Literal)
+		}
+		if len(l.Errors()) != 1 || l.Errors()[0] != tt.wantError {
+			t.Errorf("cases[%d]: errors wrong, want=%q, got=%q", i, tt.wantError, l.Errors())
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	in := "let café = 1;\nlet 変数2 = café + x1;\n€"
+
+	cases := []struct {
+		wantType    token.TokenType
+		wantLiteral string
+		wantColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "café", 5},
+		{token.ASSIGN, "=", 10},
+		{token.INT, "1", 12},
+		{token.SEMICOLON, ";", 13},
+		{token.LET, "let", 1},
+		{token.IDENT, "変数2", 5},
+		{token.ASSIGN, "=", 9},
+		{token.IDENT, "café", 11},
+		{token.PLUS, "+", 16},
+		{token.IDENT, "x1", 18},
+		{token.SEMICOLON, ";", 20},
+		{token.ILLEGAL, "€", 1},
+		{token.EOF, "", 2},
+	}
+
+	l := lexer.New(in)
+
+	for i, tt := range cases {
+		tok := l.NextToken()
+		if tok.Type != tt.wantType {
+			t.Fatalf("cases[%d]: token type wrong, want=%q, got=%q", i, tt.wantType, tok.Type)
+		}
+		if tok.Literal != tt.wantLiteral {
+			t.Fatalf("cases[%d]: token literal wrong, want=%q, got=%q", i, tt.wantLiteral, tok.Literal)
+		}
+		if tok.Column != tt.wantColumn {
+			t.Fatalf("cases[%d]: token column wrong, want=%d, got=%d", i, tt.wantColumn, tok.Column)
+		}
+	}
+	if len(l.Errors()) != 0 {
+		t.Fatalf("lexer has errors: %q", l.Errors())
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	in := "let a = 1;\nlet b\xff = 2;"
+
+	l := lexer.New(in)
+	var illegal token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			illegal = tok
+		}
+	}
+	if illegal.Literal != "\xff" || illegal.Line != 2 || illegal.Column != 6 {
+		t.Fatalf("ILLEGAL token wrong. got=%+v", illegal)
+	}
+	want := `2:6: invalid UTF-8 encoding "\xff"`
+	if len(l.Errors()) != 1 || l.Errors()[0] != want {
+		t.Fatalf("errors wrong, want=%q, got=%q", want, l.Errors())
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	l := lexer.New("let s = \"abc")
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	want := "1:9: unterminated string literal"
+	if len(l.Errors()) != 1 || l.Errors()[0] != want {
+		t.Fatalf("errors wrong, want=%q, got=%q", want, l.Errors())
+	}
+}
@@ -0,0 +1,118 @@
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kiki-ki/go-monkey/ast"
+	"github.com/kiki-ki/go-monkey/lexer"
+	"github.com/kiki-ki/go-monkey/parser"
+)
+
+// モジュールローダー
+// import "lib/strings.mk" as s; のパスを
+//   1. importしているファイルのディレクトリ
+//   2. 検索パスの各ディレクトリ
+// の順に探し, 見つかったファイルを構文解析する
+// 読み込んだモジュールは絶対パスごとにキャッシュし, 循環したimportはエラーにする
+
+type Module struct {
+	Path    string // 解決済みの絶対パス
+	Program *ast.Program
+	Imports map[string]*Module // 別名 => モジュール
+	Exports []string
+}
+
+type Loader struct {
+	SearchPath []string
+	modules    map[string]*Module
+	loading    []string // 読み込み中のモジュールの絶対パス. 循環の検出に使う
+}
+
+func New(searchPath ...string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    make(map[string]*Module),
+	}
+}
+
+// pathのモジュールとそのimportを再帰的に読み込む
+// importerはimportしているファイルのパス. エントリーポイントであれば空文字
+func (l *Loader) Load(path, importer string) (*Module, error) {
+	resolved, err := l.resolve(path, importer)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := l.modules[resolved]; ok {
+		return m, nil
+	}
+	for i, loading := range l.loading {
+		if loading == resolved {
+			chain := append(l.loading[i:len(l.loading):len(l.loading)], resolved)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	src, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", resolved, strings.Join(p.Errors(), "\n\t"))
+	}
+
+	m := &Module{Path: resolved, Program: program, Imports: make(map[string]*Module)}
+	l.loading = append(l.loading, resolved)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+	for _, s := range program.Statements {
+		switch s := s.(type) {
+		case *ast.ImportStatement:
+			imported, err := l.Load(s.Path.Value, resolved)
+			if err != nil {
+				return nil, err
+			}
+			m.Imports[s.Alias.Value] = imported
+		case *ast.ExportStatement:
+			for _, name := range s.Names {
+				m.Exports = append(m.Exports, name.Value)
+			}
+		}
+	}
+	l.modules[resolved] = m
+	return m, nil
+}
+
+// 見つかったファイルの絶対パスを返す
+// 同じファイルが相対パスと絶対パスの両方から参照されても同じモジュールになる
+func (l *Loader) resolve(path, importer string) (string, error) {
+	if filepath.IsAbs(path) {
+		if !isFile(path) {
+			return "", fmt.Errorf("module %q not found", path)
+		}
+		return filepath.Clean(path), nil
+	}
+	candidates := make([]string, 0, len(l.SearchPath)+1)
+	if importer != "" {
+		candidates = append(candidates, filepath.Join(filepath.Dir(importer), path))
+	} else {
+		candidates = append(candidates, filepath.Clean(path))
+	}
+	for _, dir := range l.SearchPath {
+		candidates = append(candidates, filepath.Join(dir, path))
+	}
+	for _, c := range candidates {
+		if isFile(c) {
+			return filepath.Abs(c)
+		}
+	}
+	return "", fmt.Errorf("module %q not found in %s", path, strings.Join(candidates, ", "))
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,172 @@
+package loader_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kiki-ki/go-monkey/loader"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadResolvesRelativeToImporter(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":        `import "lib/strings.mk" as s; s.upper("a");`,
+		"lib/strings.mk": `import "util.mk" as u; export let upper = fn(x) { u.upper(x) }; let private = 1;`,
+		"lib/util.mk":    `export const upper = fn(x) { x }; export enum E { A, B(x) };`,
+	})
+
+	m, err := loader.New().Load(filepath.Join(dir, "main.mk"), "")
+	if err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+	s, ok := m.Imports["s"]
+	if !ok {
+		t.Fatalf("m.Imports does not contain s. got=%v", m.Imports)
+	}
+	if s.Path != filepath.Join(dir, "lib", "strings.mk") {
+		t.Errorf("s.Path wrong. got=%q", s.Path)
+	}
+	if !reflect.DeepEqual(s.Exports, []string{"upper"}) {
+		t.Errorf("s.Exports wrong. got=%q", s.Exports)
+	}
+	u, ok := s.Imports["u"]
+	if !ok {
+		t.Fatalf("s.Imports does not contain u. got=%v", s.Imports)
+	}
+	if !reflect.DeepEqual(u.Exports, []string{"upper", "E", "A", "B"}) {
+		t.Errorf("u.Exports wrong. got=%q", u.Exports)
+	}
+}
+
+func TestLoadUsesSearchPath(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"app/main.mk":  `import "math.mk" as m;`,
+		"std/math.mk":  `export let pi = 3.14;`,
+		"app/other.mk": `import "missing.mk" as m;`,
+	})
+
+	l := loader.New(filepath.Join(dir, "std"))
+	m, err := l.Load(filepath.Join(dir, "app", "main.mk"), "")
+	if err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+	if m.Imports["m"].Path != filepath.Join(dir, "std", "math.mk") {
+		t.Errorf("m.Imports[m].Path wrong. got=%q", m.Imports["m"].Path)
+	}
+
+	_, err = l.Load(filepath.Join(dir, "app", "other.mk"), "")
+	if err == nil || !strings.HasPrefix(err.Error(), `module "missing.mk" not found in `) {
+		t.Errorf("error wrong. got=%v", err)
+	}
+}
+
+func TestLoadCachesModules(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk":      `import "b.mk" as b; import "c.mk" as c;`,
+		"b.mk":      `import "shared.mk" as s;`,
+		"c.mk":      `import "shared.mk" as s;`,
+		"shared.mk": `export let x = 1;`,
+	})
+
+	m, err := loader.New().Load(filepath.Join(dir, "a.mk"), "")
+	if err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+	if m.Imports["b"].Imports["s"] != m.Imports["c"].Imports["s"] {
+		t.Errorf("shared.mk was loaded twice")
+	}
+}
+
+// 作業ディレクトリをdirに移し, テスト終了時に戻す
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestLoadNormalizesRelativeAndAbsolutePaths(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":  `import "lib/a.mk" as a; import "b.mk" as b;`,
+		"lib/a.mk": `export let x = 1;`,
+		"lib/b.mk": `import "a.mk" as a;`,
+	})
+	chdir(t, dir)
+
+	// lib/a.mkは相対パスの main.mk から, lib/b.mkは絶対パスの検索パスから辿られる
+	m, err := loader.New(filepath.Join(dir, "lib")).Load("main.mk", "")
+	if err != nil {
+		t.Fatalf("Load returned error: %s", err)
+	}
+	if m.Path != filepath.Join(dir, "main.mk") {
+		t.Errorf("m.Path is not absolute. got=%q", m.Path)
+	}
+	if m.Imports["a"] != m.Imports["b"].Imports["a"] {
+		t.Errorf("lib/a.mk was loaded twice: %q and %q", m.Imports["a"].Path, m.Imports["b"].Imports["a"].Path)
+	}
+}
+
+func TestLoadDetectsImportCycleAcrossPathSpellings(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"lib/a.mk": `import "lib/b.mk" as b;`,
+		"lib/b.mk": `import "a.mk" as a;`,
+	})
+	chdir(t, dir)
+
+	_, err := loader.New(dir).Load("lib/a.mk", "")
+	a, b := filepath.Join(dir, "lib", "a.mk"), filepath.Join(dir, "lib", "b.mk")
+	want := "import cycle: " + a + " -> " + b + " -> " + a
+	if err == nil || err.Error() != want {
+		t.Errorf("error wrong. want=%q, got=%v", want, err)
+	}
+}
+
+func TestLoadDetectsImportCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk": `import "b.mk" as b;`,
+		"b.mk": `import "c.mk" as c;`,
+		"c.mk": `import "b.mk" as b;`,
+	})
+
+	_, err := loader.New().Load(filepath.Join(dir, "a.mk"), "")
+	b, c := filepath.Join(dir, "b.mk"), filepath.Join(dir, "c.mk")
+	want := "import cycle: " + b + " -> " + c + " -> " + b
+	if err == nil || err.Error() != want {
+		t.Errorf("error wrong. want=%q, got=%v", want, err)
+	}
+}
+
+func TestLoadReportsParseErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.mk": `import "b.mk" as b;`,
+		"b.mk": `export 1;`,
+	})
+
+	_, err := loader.New().Load(filepath.Join(dir, "a.mk"), "")
+	want := filepath.Join(dir, "b.mk") + ": 1:8: expected let, const, struct or enum after export. got INT"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("error wrong. want=%q, got=%v", want, err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
@@ -9,6 +10,9 @@ import (
 )
 
 func main() {
+	noOpt := flag.Bool("no-opt", false, "disable AST optimization (for debugging)")
+	flag.Parse()
+
 	u, err := user.Current()
 	if err != nil {
 		panic(err.Error())
@@ -17,5 +21,5 @@ func main() {
 	fmt.Println("Usage:")
 	fmt.Printf("\tHelp: 'h' or 'help'\n")
 	fmt.Printf("\tEscape: 'q' or 'exit'\n\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, !*noOpt)
 }
@@ -0,0 +1,376 @@
+package optimizer
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/kiki-ki/go-monkey/ast"
+	"github.com/kiki-ki/go-monkey/token"
+)
+
+// AST最適化
+// - 数値/真偽値リテラル同士の前置・中置演算の定数畳み込み(&&, ||は短絡評価を考慮)
+//   整数はint64を溢れると多倍長整数に昇格し、収まれば戻す
+// - 条件が定数のif式, 三項演算子から到達不能な分岐を除去
+// - ブロック内のreturn, throw, break, continue以降の文を除去
+
+func Optimize(program *ast.Program) *ast.Program {
+	program.Statements = optimizeStatements(program.Statements)
+	return program
+}
+
+func optimizeStatements(ss []ast.Statement) []ast.Statement {
+	for i, s := range ss {
+		ss[i] = optimizeStatement(s)
+	}
+	return ss
+}
+
+func optimizeStatement(s ast.Statement) ast.Statement {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		s.Value = optimizeExpression(s.Value)
+	case *ast.ConstStatement:
+		s.Value = optimizeExpression(s.Value)
+	case *ast.StructStatement:
+		for _, m := range s.Methods {
+			optimizeExpression(m.Function)
+		}
+	case *ast.ReturnStatement:
+		s.ReturnValue = optimizeExpression(s.ReturnValue)
+	case *ast.ExpressionStatement:
+		s.Expression = optimizeExpression(s.Expression)
+	case *ast.WhileStatement:
+		s.Condition = optimizeExpression(s.Condition)
+		optimizeBlock(s.Body)
+	case *ast.ForStatement:
+		s.Iterable = optimizeExpression(s.Iterable)
+		optimizeBlock(s.Body)
+	case *ast.ThrowStatement:
+		s.Value = optimizeExpression(s.Value)
+	case *ast.DeferStatement:
+		s.Value = optimizeExpression(s.Value)
+	case *ast.TryStatement:
+		optimizeBlock(s.Block)
+		optimizeBlock(s.Catch)
+		optimizeBlock(s.Finally)
+	case *ast.ExportStatement:
+		s.Statement = optimizeStatement(s.Statement)
+	case *ast.BlockStatement:
+		optimizeBlock(s)
+	}
+	return s
+}
+
+func optimizeBlock(b *ast.BlockStatement) {
+	if b == nil {
+		return
+	}
+	b.Statements = optimizeStatements(b.Statements)
+	for i, s := range b.Statements {
+		if isTerminal(s) {
+			b.Statements = b.Statements[:i+1]
+			break
+		}
+	}
+}
+
+// 後続の文に制御が移らない文か判断
+func isTerminal(s ast.Statement) bool {
+	switch s.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	}
+	return false
+}
+
+func optimizeExpression(e ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		e.Right = optimizeExpression(e.Right)
+		return foldPrefix(e)
+	case *ast.InfixExpression:
+		e.Left = optimizeExpression(e.Left)
+		e.Right = optimizeExpression(e.Right)
+		return foldInfix(e)
+	case *ast.AssignExpression:
+		e.Value = optimizeExpression(e.Value)
+	case *ast.IfExpression:
+		e.Condition = optimizeExpression(e.Condition)
+		optimizeBlock(e.Consequence)
+		optimizeBlock(e.Alternative)
+		return pruneIf(e)
+	case *ast.ConditionalExpression:
+		e.Condition = optimizeExpression(e.Condition)
+		e.Consequence = optimizeExpression(e.Consequence)
+		e.Alternative = optimizeExpression(e.Alternative)
+		if truthy, ok := constantTruthiness(e.Condition); ok {
+			if truthy {
+				return e.Consequence
+			}
+			return e.Alternative
+		}
+	case *ast.MatchExpression:
+		e.Subject = optimizeExpression(e.Subject)
+		for _, arm := range e.Arms {
+			arm.Guard = optimizeExpression(arm.Guard)
+			arm.Body = optimizeExpression(arm.Body)
+		}
+	case *ast.FunctionLiteral:
+		for name, d := range e.Defaults {
+			e.Defaults[name] = optimizeExpression(d)
+		}
+		optimizeBlock(e.Body)
+	case *ast.KeywordArgument:
+		e.Value = optimizeExpression(e.Value)
+	case *ast.MemberExpression:
+		e.Object = optimizeExpression(e.Object)
+	case *ast.StructLiteral:
+		for _, f := range e.Fields {
+			f.Value = optimizeExpression(f.Value)
+		}
+	case *ast.CallExpression:
+		e.Function = optimizeExpression(e.Function)
+		for i, arg := range e.Arguments {
+			e.Arguments[i] = optimizeExpression(arg)
+		}
+	}
+	return e
+}
+
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch right := pe.Right.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntLiteral:
+		switch pe.Operator {
+		case "-":
+			v, _ := integerValue(right)
+			return newInteger(v.Neg(v))
+		case "!":
+			return newBoolean(false)
+		}
+	case *ast.FloatLiteral:
+		switch pe.Operator {
+		case "-":
+			return newFloat(-right.Value)
+		case "!":
+			return newBoolean(false)
+		}
+	case *ast.Boolean:
+		if pe.Operator == "!" {
+			return newBoolean(!right.Value)
+		}
+	}
+	return pe
+}
+
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	// リテラルはnullにならないので右辺は評価されない
+	if ie.Operator == "??" {
+		if _, ok := constantTruthiness(ie.Left); ok {
+			return ie.Left
+		}
+		return ie
+	}
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral, *ast.BigIntLiteral, *ast.FloatLiteral:
+		l, lok := integerValue(left)
+		r, rok := integerValue(ie.Right)
+		if lok && rok {
+			if folded := foldIntegerInfix(ie.Operator, l, r); folded != nil {
+				return folded
+			}
+			return ie
+		}
+		// 整数と浮動小数点数が混在する場合は浮動小数点数に昇格する
+		lf, lok := floatValue(ie.Left)
+		rf, rok := floatValue(ie.Right)
+		if lok && rok {
+			if folded := foldFloatInfix(ie.Operator, lf, rf); folded != nil {
+				return folded
+			}
+		}
+	case *ast.Boolean:
+		// 短絡評価で右辺が評価されない場合は右辺によらず畳み込める
+		switch {
+		case ie.Operator == "&&" && !left.Value:
+			return newBoolean(false)
+		case ie.Operator == "||" && left.Value:
+			return newBoolean(true)
+		}
+		if right, ok := ie.Right.(*ast.Boolean); ok {
+			switch ie.Operator {
+			case "==":
+				return newBoolean(left.Value == right.Value)
+			case "!=":
+				return newBoolean(left.Value != right.Value)
+			case "&&":
+				return newBoolean(left.Value && right.Value)
+			case "||":
+				return newBoolean(left.Value || right.Value)
+			}
+		}
+	}
+	return ie
+}
+
+// 整数演算は多倍長で行い、結果がint64に収まればIntegerLiteralに戻す
+func foldIntegerInfix(operator string, left, right *big.Int) ast.Expression {
+	switch operator {
+	case "+":
+		return newInteger(new(big.Int).Add(left, right))
+	case "-":
+		return newInteger(new(big.Int).Sub(left, right))
+	case "*":
+		return newInteger(new(big.Int).Mul(left, right))
+	case "/":
+		// ゼロ除算は実行時エラーとして残す
+		if right.Sign() == 0 {
+			return nil
+		}
+		return newInteger(new(big.Int).Quo(left, right))
+	case "%":
+		// 剰余の符号は被除数に合わせる(-7 % 3 == -1)
+		if right.Sign() == 0 {
+			return nil
+		}
+		return newInteger(new(big.Int).Rem(left, right))
+	case "<":
+		return newBoolean(left.Cmp(right) < 0)
+	case ">":
+		return newBoolean(left.Cmp(right) > 0)
+	case "<=":
+		return newBoolean(left.Cmp(right) <= 0)
+	case ">=":
+		return newBoolean(left.Cmp(right) >= 0)
+	case "==":
+		return newBoolean(left.Cmp(right) == 0)
+	case "!=":
+		return newBoolean(left.Cmp(right) != 0)
+	}
+	return nil
+}
+
+func foldFloatInfix(operator string, left, right float64) ast.Expression {
+	switch operator {
+	case "+":
+		return newFloat(left + right)
+	case "-":
+		return newFloat(left - right)
+	case "*":
+		return newFloat(left * right)
+	case "/":
+		if right == 0 {
+			return nil
+		}
+		return newFloat(left / right)
+	case "%":
+		if right == 0 {
+			return nil
+		}
+		return newFloat(math.Mod(left, right))
+	case "<":
+		return newBoolean(left < right)
+	case ">":
+		return newBoolean(left > right)
+	case "<=":
+		return newBoolean(left <= right)
+	case ">=":
+		return newBoolean(left >= right)
+	case "==":
+		return newBoolean(left == right)
+	case "!=":
+		return newBoolean(left != right)
+	}
+	return nil
+}
+
+func integerValue(e ast.Expression) (*big.Int, bool) {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return big.NewInt(e.Value), true
+	case *ast.BigIntLiteral:
+		return new(big.Int).Set(e.Value), true
+	}
+	return nil, false
+}
+
+func floatValue(e ast.Expression) (float64, bool) {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return float64(e.Value), true
+	case *ast.BigIntLiteral:
+		f, _ := new(big.Float).SetInt(e.Value).Float64()
+		return f, true
+	case *ast.FloatLiteral:
+		return e.Value, true
+	}
+	return 0, false
+}
+
+func pruneIf(ie *ast.IfExpression) ast.Expression {
+	truthy, ok := constantTruthiness(ie.Condition)
+	if !ok {
+		return ie
+	}
+	if truthy {
+		ie.Alternative = nil
+		return ie
+	}
+	if ie.Alternative != nil {
+		ie.Condition = newBoolean(true)
+		ie.Consequence = ie.Alternative
+		ie.Alternative = nil
+		return ie
+	}
+	ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token, Statements: []ast.Statement{}}
+	return ie
+}
+
+// 条件式が定数であればその真偽を返す
+func constantTruthiness(e ast.Expression) (bool, bool) {
+	switch e := e.(type) {
+	case *ast.Boolean:
+		return e.Value, true
+	case *ast.IntegerLiteral, *ast.BigIntLiteral, *ast.FloatLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+func newInteger(v *big.Int) ast.Expression {
+	tok := token.Token{Type: token.INT, Literal: v.String()}
+	if v.IsInt64() {
+		return &ast.IntegerLiteral{Token: tok, Value: v.Int64()}
+	}
+	return &ast.BigIntLiteral{Token: tok, Value: v}
+}
+
+// 無限大やNaNはリテラルで表せないため畳み込まない
+func newFloat(v float64) ast.Expression {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+	return &ast.FloatLiteral{
+		Token: token.Token{Type: token.FLOAT, Literal: formatFloat(v)},
+		Value: v,
+	}
+}
+
+// 再び字句解析したときにFLOATとなるよう小数点か指数を必ず含める
+func formatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func newBoolean(v bool) *ast.Boolean {
+	tok := token.Token{Type: token.FALSE, Literal: "false"}
+	if v {
+		tok = token.Token{Type: token.TRUE, Literal: "true"}
+	}
+	return &ast.Boolean{Token: tok, Value: v}
+}
@@ -0,0 +1,171 @@
+package optimizer_test
+
+import (
+	"testing"
+
+	"github.com/kiki-ki/go-monkey/ast"
+	"github.com/kiki-ki/go-monkey/lexer"
+	"github.com/kiki-ki/go-monkey/optimizer"
+	"github.com/kiki-ki/go-monkey/parser"
+)
+
+func TestConstantFolding(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"2 * 3 + 1", "7"},
+		{"1 + 2 * 3 - 4 / 2", "5"},
+		{"-(5 + 5)", "-10"},
+		{"!true", "false"},
+		{"!!false", "false"},
+		{"!5", "false"},
+		{"1 < 2 == true", "true"},
+		{"3 > 5 != false", "false"},
+		{"a + 2 * 3", "(a + 6)"},
+		{"1 + a + 2", "((1 + a) + 2)"},
+		{"10 / 0", "(10 / 0)"},
+		{"1 == true", "(1 == true)"},
+		{"true && !false", "true"},
+		{"1 > 2 || 2 > 1", "true"},
+		{"false && f()", "false"},
+		{"true || f()", "true"},
+		{"true && f()", "(true && f())"},
+		{"7 % 3", "1"},
+		{"-7 % 3", "-1"},
+		{"7 % -3", "1"},
+		{"7 % 0", "(7 % 0)"},
+		{"2 <= 2", "true"},
+		{"1 >= 2", "false"},
+		{"0.5 + 0.25", "0.75"},
+		{"1 + 0.5", "1.5"},
+		{"3 * 0.5", "1.5"},
+		{"1.5 * 2", "3.0"},
+		{"1 / 2.0", "0.5"},
+		{"7.5 % 2", "1.5"},
+		{"-2.5", "-2.5"},
+		{"1e300 * 1e300", "(1e300 * 1e300)"},
+		{"1.0 / 0", "(1.0 / 0)"},
+		{"1 < 1.5", "true"},
+		{"2.0 == 2", "true"},
+		{"!0.5", "false"},
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"-9223372036854775808", "-9223372036854775808"},
+		{"9223372036854775808 - 1", "9223372036854775807"},
+		{"18446744073709551616 / 4294967296", "4294967296"},
+		{"18446744073709551617 % 10", "7"},
+		{"18446744073709551616 > 1", "true"},
+		{"18446744073709551616 * 0.5", "9.223372036854776e+18"},
+		{"18446744073709551616 / 0", "(18446744073709551616 / 0)"},
+		{"add(1 + 2, x * (3 - 1))", "add(3, (x * 2))"},
+		{"let x = 60 * 60 * 24;", "let x = 86400;"},
+		{"x += 2 * 3", "(x += 6)"},
+	}
+
+	for _, tt := range cases {
+		got := optimize(t, tt.input).String()
+		if got != tt.want {
+			t.Errorf("want=%q, got=%q", tt.want, got)
+		}
+	}
+}
+
+func TestIntegerDemotion(t *testing.T) {
+	cases := []struct {
+		input string
+		want  interface{}
+	}{
+		{"9223372036854775808 - 1", int64(9223372036854775807)},
+		{"-9223372036854775808", int64(-9223372036854775808)},
+		{"9223372036854775807 + 1", "9223372036854775808"},
+	}
+
+	for _, tt := range cases {
+		program := optimize(t, tt.input)
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+		switch want := tt.want.(type) {
+		case int64:
+			il, ok := exp.(*ast.IntegerLiteral)
+			if !ok {
+				t.Fatalf("exp is not *ast.IntegerLiteral. got=%T", exp)
+			}
+			if il.Value != want {
+				t.Errorf("il.Value is not %d. got=%d", want, il.Value)
+			}
+		case string:
+			bl, ok := exp.(*ast.BigIntLiteral)
+			if !ok {
+				t.Fatalf("exp is not *ast.BigIntLiteral. got=%T", exp)
+			}
+			if bl.Value.String() != want {
+				t.Errorf("bl.Value is not %s. got=%s", want, bl.Value)
+			}
+		}
+	}
+}
+
+func TestDeadBranchElimination(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"if (true) { x } else { y }", "iftrue x"},
+		{"if (1 < 2) { x } else { y }", "iftrue x"},
+		{"if (false) { x } else { y }", "iftrue y"},
+		{"if (false) { x }", "iffalse "},
+		{"if (5) { x } else { y }", "if5 x"},
+		{"if (a) { x } else { y }", "ifa xelse y"},
+		{"if (1 > 2) { x } else { if (true) { y } else { z } }", "iftrue iftrue y"},
+		{"if (a) { x } else if (1 < 2) { y } else { z }", "ifa xelse iftrue y"},
+		{"true ? x : y", "x"},
+		{"1 > 2 ? x : y", "y"},
+		{"a ? 1 + 1 : y", "(a ? 2 : y)"},
+		{"1 ?? f()", "1"},
+		{"a ?? 2 * 2", "(a ?? 4)"},
+		{"match (1 + 1) { 2 if 1 < 2 => 3 * 3, _ => 0 }", "match(2) { 2 if true => 9, _ => 0 }"},
+		{"if (false) { x } else if (b) { y } else { z }", "iftrue ifb yelse z"},
+	}
+
+	for _, tt := range cases {
+		got := optimize(t, tt.input).String()
+		if got != tt.want {
+			t.Errorf("want=%q, got=%q", tt.want, got)
+		}
+	}
+}
+
+func TestUnreachableStatementsAfterReturn(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"fn() { return 1; 2; 3 }", "fn() return 1;"},
+		{"fn() { let a = 1; return a; a + 1 }", "fn() let a = 1;return a;"},
+		{"fn() { if (x) { return 1; 2 } 3 }", "fn() ifx return 1;3"},
+		{"fn() { 1 + 1 }", "fn() 2"},
+		{"while (x) { break; x }", "whilex break;"},
+		{"for (x in xs) { continue; x; }", "for(x in xs) continue;"},
+		{"while (x < 2 * 5) { x += 1 }", "while(x < 10) (x += 1)"},
+		{"fn() { throw 1 + 1; 2 }", "fn() throw 2;"},
+		{"try { throw e; f() } catch (e) { return 1; g() } finally { 1 * 2 }", "try throw e; catch(e) return 1; finally 2"},
+	}
+
+	for _, tt := range cases {
+		got := optimize(t, tt.input).String()
+		if got != tt.want {
+			t.Errorf("want=%q, got=%q", tt.want, got)
+		}
+	}
+}
+
+func optimize(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+	return optimizer.Optimize(program)
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/kiki-ki/go-monkey/ast"
@@ -13,24 +15,46 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	PIPE        // |>
+	ASSIGN      // =, +=, -=, *=, /=
+	TERNARY     // a ? b : c
+	COALESCE    // ??
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
-	LESSGREATER // > or <
+	LESSGREATER // >, <, >= or <=
 	SUM         // +, -
-	PRODUCT     // *, /
+	PRODUCT     // *, /, %
 	PREFIX      // -x or !x
 	CALL        // add(x)
+	MEMBER      // p.x or Point{x: 1}
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.ASTERISK: PRODUCT,
-	token.SLASH:    PRODUCT,
-	token.LPAREN:   CALL,
+	token.PIPE:            PIPE,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.QUESTION:        TERNARY,
+	token.COALESCE:        COALESCE,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.ASTERISK:        PRODUCT,
+	token.SLASH:           PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.DOT:             MEMBER,
+	token.LBRACE:          MEMBER,
 }
 
 type (
@@ -39,9 +63,43 @@ type (
 	// sufixParseFn func() ast.Expression
 )
 
+// 関数ごとのスコープ
+type scope struct {
+	bindings map[string]binding
+	// まだ束縛されていない名前への代入. 後からconstで宣言されていないかスコープを抜けるときに検査する
+	unresolved []*ast.Identifier
+}
+
+func newScope() *scope {
+	return &scope{bindings: make(map[string]binding)}
+}
+
+// スコープ内の名前の束縛
+type binding struct {
+	isConst bool
+	variant *variant // enumのバリアントのコンストラクタであれば非nil
+}
+
+type variant struct {
+	arity    int
+	siblings []string // 同じenumの全バリアント名. matchの網羅性の検査に使う
+}
+
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l        *lexer.Lexer
+	errors   []string
+	warnings []string
+
+	loopDepth  int  // break, continueが使えるループの深さ
+	blockDepth int  // import, exportはブロックの外でのみ使える
+	funcDepth  int  // deferが使える関数の深さ
+	inGuard    bool // matchのガード中は x => をアロー関数として扱わない
+
+	// 関数ごとの束縛. enumのバリアントもここに束縛されるので関数の外には見えない
+	// if, whileなどのブロックは新しいスコープを作らない
+	scopes []*scope
+	// 宣言済みの構造体のフィールド. 構造体リテラルのフィールド名を検査する
+	structs map[string][]string
 
 	curToken  token.Token
 	peekToken token.Token
@@ -52,12 +110,16 @@ type Parser struct {
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: make([]string, 0),
+		l:       l,
+		errors:  make([]string, 0),
+		scopes:  []*scope{newScope()},
+		structs: make(map[string][]string),
 	}
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
@@ -65,17 +127,33 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.LBRACE, p.parseStructLiteral)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 
 	// cur, peekがセットされてる状態まで進めておく
 	p.nextToken()
@@ -83,8 +161,27 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// 字句解析器のエラーと構文解析器のエラーを返す
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := make([]string, 0, len(p.l.Errors())+len(p.errors))
+	errors = append(errors, p.l.Errors()...)
+	return append(errors, p.errors...)
+}
+
+// 構文としては正しいが疑わしい箇所への警告を返す
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
+func (p *Parser) positionedWarning(tok token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", tok.Line, tok.Column) + fmt.Sprintf(format, a...)
+	p.warnings = append(p.warnings, msg)
+}
+
+// 行番号:列番号を先頭に付けたエラーを記録する
+func (p *Parser) positionedError(tok token.Token, format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: ", tok.Line, tok.Column) + fmt.Sprintf(format, a...)
+	p.errors = append(p.errors, msg)
 }
 
 func (p *Parser) peekError(t token.TokenType) {
@@ -101,53 +198,751 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = make([]ast.Statement, 0)
 
-	for !p.curTokenIs(token.EOF) {
-		s := p.parseStatement()
-		if s != nil {
-			program.Statements = append(program.Statements, s)
+	for !p.curTokenIs(token.EOF) {
+		s := p.parseStatement()
+		if s != nil {
+			program.Statements = append(program.Statements, s)
+		}
+		p.nextToken()
+	}
+	p.resolveAssignments(p.scopes[0], nil)
+
+	return program
+}
+
+func (p *Parser) parseStatement() ast.Statement {
+	switch p.curToken.Type {
+	case token.LET:
+		return p.parseLetStatement()
+	case token.CONST:
+		return p.parseConstStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.RETURN:
+		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.TRY:
+		return p.parseTryStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
+	default:
+		return p.parseExpressionStatement()
+	}
+}
+
+func (p *Parser) parseLetStatement() *ast.LetStatement {
+	s := &ast.LetStatement{Token: p.curToken}
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		s.Pattern = p.parsePattern()
+		if s.Pattern == nil || !p.checkDuplicateBindings(s.Pattern) {
+			return nil
+		}
+		if tok, refutable := refutablePattern(s.Pattern); refutable != nil {
+			p.positionedError(tok, "refutable pattern %s in let", refutable)
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		s.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	s.Value = p.parseExpression(LOWEST)
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	bindings := []*ast.Identifier{s.Name}
+	if s.Pattern != nil {
+		bindings = patternBindings(s.Pattern)
+	}
+	for _, ident := range bindings {
+		if !p.declare(ident, false) {
+			return nil
+		}
+	}
+	return s
+}
+
+// const x = 5;
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+	s := &ast.ConstStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	s.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	s.Value = p.parseExpression(LOWEST)
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if !p.declare(s.Name, true) {
+		return nil
+	}
+	return s
+}
+
+// struct Point { x, y, fn norm(self) { ... } }
+// Point(1, 2)またはPoint{x: 1, y: 2}で生成する
+// メソッドの後ろのカンマは省略できる
+func (p *Parser) parseStructStatement() *ast.StructStatement {
+	s := &ast.StructStatement{
+		Token:   p.curToken,
+		Fields:  make([]*ast.Identifier, 0),
+		Methods: make([]*ast.StructMethod, 0),
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	s.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.FUNCTION) {
+			p.nextToken()
+			method := p.parseStructMethod()
+			if method == nil {
+				return nil
+			}
+			if seen[method.Name.Value] {
+				p.positionedError(method.Name.Token, "duplicate method %s in struct %s", method.Name.Value, s.Name.Value)
+				return nil
+			}
+			seen[method.Name.Value] = true
+			s.Methods = append(s.Methods, method)
+			if p.peekTokenIs(token.COMMA) {
+				p.nextToken()
+			}
+			continue
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[field.Value] {
+			p.positionedError(field.Token, "duplicate field %s in struct %s", field.Value, s.Name.Value)
+			return nil
+		}
+		seen[field.Value] = true
+		s.Fields = append(s.Fields, field)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if !p.declare(s.Name, false) {
+		return nil
+	}
+	fields := make([]string, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		fields = append(fields, f.Value)
+	}
+	p.structs[s.Name.Value] = fields
+	return s
+}
+
+// fn norm(self) { ... }
+func (p *Parser) parseStructMethod() *ast.StructMethod {
+	fn := &ast.FunctionLiteral{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	method := &ast.StructMethod{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	if !p.parseFunctionBody(fn) {
+		return nil
+	}
+	if len(fn.Parameters) == 0 {
+		p.positionedError(method.Name.Token, "method %s must take the receiver as its first parameter", method.Name.Value)
+		return nil
+	}
+	method.Function = fn
+	return method
+}
+
+// enum Shape { Circle(r), Rect(w, h), Empty }
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	s := &ast.EnumStatement{Token: p.curToken, Variants: make([]*ast.EnumVariant, 0)}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	s.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		v := p.parseEnumVariant()
+		if v == nil {
+			return nil
+		}
+		if seen[v.Name.Value] {
+			p.positionedError(v.Name.Token, "duplicate variant %s in enum %s", v.Name.Value, s.Name.Value)
+			return nil
+		}
+		seen[v.Name.Value] = true
+		s.Variants = append(s.Variants, v)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if !p.declare(s.Name, false) {
+		return nil
+	}
+	names := make([]string, 0, len(s.Variants))
+	for _, v := range s.Variants {
+		names = append(names, v.Name.Value)
+	}
+	for _, v := range s.Variants {
+		if !p.bind(v.Name, binding{variant: &variant{arity: len(v.Fields), siblings: names}}) {
+			return nil
+		}
+	}
+	return s
+}
+
+// Circle(r) または Empty
+func (p *Parser) parseEnumVariant() *ast.EnumVariant {
+	v := &ast.EnumVariant{
+		Name:   &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+		Fields: make([]*ast.Identifier, 0),
+	}
+	if !p.peekTokenIs(token.LPAREN) {
+		return v
+	}
+	p.nextToken()
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[field.Value] {
+			p.positionedError(field.Token, "duplicate field %s in variant %s", field.Value, v.Name.Value)
+			return nil
+		}
+		seen[field.Value] = true
+		v.Fields = append(v.Fields, field)
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return v
+}
+
+// import "lib/strings.mk" as s;
+// パスの解決と読み込みはloaderパッケージが行う
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	s := &ast.ImportStatement{Token: p.curToken}
+	if p.blockDepth > 0 {
+		p.positionedError(s.Token, "import is only allowed at the top level")
+		return nil
+	}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	s.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	if s.Path.Value == "" {
+		p.positionedError(s.Path.Token, "import path must not be empty")
+		return nil
+	}
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	s.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	// モジュールは再代入できない
+	if !p.declare(s.Alias, true) {
+		return nil
+	}
+	return s
+}
+
+// export let x = 1; export const y = 2; export struct P { ... }; export enum E { ... }
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	s := &ast.ExportStatement{Token: p.curToken}
+	if p.blockDepth > 0 {
+		p.positionedError(s.Token, "export is only allowed at the top level")
+		return nil
+	}
+	p.nextToken()
+	switch p.curToken.Type {
+	case token.LET:
+		ls := p.parseLetStatement()
+		if ls == nil {
+			return nil
+		}
+		s.Statement, s.Names = ls, []*ast.Identifier{ls.Name}
+		if ls.Pattern != nil {
+			s.Names = patternBindings(ls.Pattern)
+		}
+	case token.CONST:
+		cs := p.parseConstStatement()
+		if cs == nil {
+			return nil
+		}
+		s.Statement, s.Names = cs, []*ast.Identifier{cs.Name}
+	case token.STRUCT:
+		ss := p.parseStructStatement()
+		if ss == nil {
+			return nil
+		}
+		s.Statement, s.Names = ss, []*ast.Identifier{ss.Name}
+	case token.ENUM:
+		es := p.parseEnumStatement()
+		if es == nil {
+			return nil
+		}
+		s.Statement, s.Names = es, []*ast.Identifier{es.Name}
+		for _, v := range es.Variants {
+			s.Names = append(s.Names, v.Name)
+		}
+	default:
+		p.positionedError(p.curToken, "expected let, const, struct or enum after export. got %s", p.curToken.Type)
+		return nil
+	}
+	return s
+}
+
+// 現在のスコープに名前を束縛する
+func (p *Parser) declare(ident *ast.Identifier, isConst bool) bool {
+	return p.bind(ident, binding{isConst: isConst})
+}
+
+// 同じスコープのconstは束縛し直せない
+func (p *Parser) bind(ident *ast.Identifier, b binding) bool {
+	s := p.scopes[len(p.scopes)-1]
+	if s.bindings[ident.Value].isConst {
+		p.positionedError(ident.Token, "cannot redeclare const %s", ident.Value)
+		return false
+	}
+	s.bindings[ident.Value] = b
+	return true
+}
+
+// 名前の最も内側の束縛を返す
+func (p *Parser) lookup(name string) (binding, bool) {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if b, ok := p.scopes[i].bindings[name]; ok {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
+
+// 名前がenumのバリアントであればその情報を返す
+// 引数などで隠されていればバリアントとして扱わない
+func (p *Parser) lookupVariant(name string) *variant {
+	b, _ := p.lookup(name)
+	return b.variant
+}
+
+// 関数の引数, matchのパターン, catchの引数で束縛される名前を持つスコープに入る
+func (p *Parser) enterScope(bindings []*ast.Identifier) {
+	s := newScope()
+	for _, ident := range bindings {
+		s.bindings[ident.Value] = binding{}
+	}
+	p.scopes = append(p.scopes, s)
+}
+
+func (p *Parser) leaveScope() {
+	s := p.scopes[len(p.scopes)-1]
+	p.scopes = p.scopes[:len(p.scopes)-1]
+	p.resolveAssignments(s, p.scopes[len(p.scopes)-1])
+}
+
+// 代入の時点で未束縛だった名前がスコープ内でconstとして宣言されていればエラーにする
+// ex: let f = fn() { x = 2 }; const x = 1;
+// スコープ内で宣言されていなければ外側のスコープに持ち越す
+func (p *Parser) resolveAssignments(s, outer *scope) {
+	for _, ident := range s.unresolved {
+		b, ok := s.bindings[ident.Value]
+		switch {
+		case ok && b.isConst:
+			p.positionedError(ident.Token, "cannot assign to const %s", ident.Value)
+		case !ok && outer != nil:
+			outer.unresolved = append(outer.unresolved, ident)
+		}
+	}
+}
+
+// 識別子, [a, b, ...rest], {name, age: years} のいずれか. 入れ子にできる
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		// 宣言済みのバリアント名は束縛ではなくバリアントにマッチする
+		if p.lookupVariant(p.curToken.Literal) != nil || p.peekTokenIs(token.LPAREN) {
+			return p.parseVariantPattern()
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		return p.parseLiteralPattern()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	}
+	p.positionedError(p.curToken, "expected pattern. got %s", p.curToken.Type)
+	return nil
+}
+
+// Circle(r), Rect(w, _), Empty
+// 宣言済みのバリアントであればフィールド数を検査する
+func (p *Parser) parseVariantPattern() ast.Pattern {
+	pattern := &ast.VariantPattern{Token: p.curToken}
+	pattern.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		pattern.Fields = make([]ast.Pattern, 0)
+		for !p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+			field := p.parsePattern()
+			if field == nil {
+				return nil
+			}
+			pattern.Fields = append(pattern.Fields, field)
+			if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+	}
+	if v := p.lookupVariant(pattern.Name.Value); v != nil && v.arity != len(pattern.Fields) {
+		p.positionedError(pattern.Token, "variant %s has %d field(s), but pattern has %d",
+			pattern.Name.Value, v.arity, len(pattern.Fields))
+		return nil
+	}
+	return pattern
+}
+
+// 0, 1.5, "a", true, -1
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	pattern := &ast.LiteralPattern{Token: p.curToken}
+	if !p.curTokenIs(token.MINUS) {
+		pattern.Value = p.prefixParseFns[p.curToken.Type]()
+		if pattern.Value == nil {
+			return nil
+		}
+		return pattern
+	}
+	// -の後ろは式ではなく数値リテラルのトークンだけを読む
+	// ex: -x => 2 をアロー関数として読み進めない
+	if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+		p.positionedError(p.peekToken, "expected number after - in pattern. got %s", p.peekToken.Type)
+		return nil
+	}
+	exp := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
+	p.nextToken()
+	exp.Right = p.prefixParseFns[p.curToken.Type]()
+	if exp.Right == nil {
+		return nil
+	}
+	pattern.Value = exp
+	return pattern
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken, Elements: make([]ast.Pattern, 0)}
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if !p.peekTokenIs(token.RBRACKET) {
+				p.positionedError(pattern.Rest.Token, "rest element ...%s must be last", pattern.Rest.Value)
+				return nil
+			}
+			break
+		}
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken, Pairs: make([]*ast.HashPatternPair, 0)}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		pair := &ast.HashPatternPair{Key: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		pair.Value = pair.Key
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if pair.Value = p.parsePattern(); pair.Value == nil {
+				return nil
+			}
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pattern
+}
+
+// パターン内で同じ名前を2回束縛していないか検査する
+func (p *Parser) checkDuplicateBindings(pattern ast.Pattern) bool {
+	seen := make(map[string]bool)
+	for _, ident := range patternBindings(pattern) {
+		if seen[ident.Value] {
+			p.positionedError(ident.Token, "duplicate binding %s in pattern", ident.Value)
+			return false
+		}
+		seen[ident.Value] = true
+	}
+	return true
+}
+
+// letなど必ずマッチしなければならない箇所で使えないパターンを探す
+func refutablePattern(pattern ast.Pattern) (token.Token, ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return pattern.Token, pattern
+	case *ast.VariantPattern:
+		return pattern.Token, pattern
+	case *ast.ArrayPattern:
+		for _, e := range pattern.Elements {
+			if tok, refutable := refutablePattern(e); refutable != nil {
+				return tok, refutable
+			}
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			if tok, refutable := refutablePattern(pair.Value); refutable != nil {
+				return tok, refutable
+			}
+		}
+	}
+	return token.Token{}, nil
+}
+
+// パターンが束縛する識別子を出現順に返す
+func patternBindings(pattern ast.Pattern) []*ast.Identifier {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return []*ast.Identifier{pattern}
+	case *ast.ArrayPattern:
+		idents := make([]*ast.Identifier, 0)
+		for _, e := range pattern.Elements {
+			idents = append(idents, patternBindings(e)...)
+		}
+		if pattern.Rest != nil {
+			idents = append(idents, pattern.Rest)
+		}
+		return idents
+	case *ast.HashPattern:
+		idents := make([]*ast.Identifier, 0)
+		for _, pair := range pattern.Pairs {
+			idents = append(idents, patternBindings(pair.Value)...)
+		}
+		return idents
+	case *ast.VariantPattern:
+		idents := make([]*ast.Identifier, 0)
+		for _, f := range pattern.Fields {
+			idents = append(idents, patternBindings(f)...)
+		}
+		return idents
+	}
+	return nil
+}
+
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	s := &ast.ReturnStatement{Token: p.curToken}
+	p.nextToken()
+	s.ReturnValue = p.parseExpression(LOWEST)
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return s
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	s := &ast.ThrowStatement{Token: p.curToken}
+	p.nextToken()
+	s.Value = p.parseExpression(LOWEST)
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return s
+}
+
+// defer close(f);
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	s := &ast.DeferStatement{Token: p.curToken}
+	if p.funcDepth == 0 {
+		p.positionedError(s.Token, "defer outside function")
+		return nil
+	}
+	p.nextToken()
+	s.Value = p.parseExpression(LOWEST)
+	for p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return s
+}
+
+// try { ... } catch (e) { ... } finally { ... }
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	s := &ast.TryStatement{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	s.Block = p.parseBlockStatement()
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		s.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
 		}
+		p.enterScope([]*ast.Identifier{s.Param})
+		s.Catch = p.parseBlockStatement()
+		p.leaveScope()
+	}
+	if p.peekTokenIs(token.FINALLY) {
 		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		s.Finally = p.parseBlockStatement()
 	}
-
-	return program
+	if s.Catch == nil && s.Finally == nil {
+		p.positionedError(s.Token, "try without catch or finally")
+		return nil
+	}
+	return s
 }
 
-func (p *Parser) parseStatement() ast.Statement {
-	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
-	case token.RETURN:
-		return p.parseReturnStatement()
-	default:
-		return p.parseExpressionStatement()
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	s := &ast.WhileStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	s.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
 	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	s.Body = p.parseLoopBody()
+	return s
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	s := &ast.LetStatement{Token: p.curToken}
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	s := &ast.ForStatement{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
-	s.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	if !p.expectPeek(token.ASSIGN) {
+	s.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.IN) {
 		return nil
 	}
 	p.nextToken()
-	s.Value = p.parseExpression(LOWEST)
-	for p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
+	s.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
 	}
+	if !p.declare(s.Variable, false) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	s.Body = p.parseLoopBody()
 	return s
 }
 
-func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
-	s := &ast.ReturnStatement{Token: p.curToken}
-	p.nextToken()
-	s.ReturnValue = p.parseExpression(LOWEST)
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	body := p.parseBlockStatement()
+	p.loopDepth--
+	return body
+}
+
+// break, continue
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
-	return s
+	if p.loopDepth == 0 {
+		p.positionedError(tok, "%s outside loop", tok.Literal)
+		return nil
+	}
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
@@ -177,6 +972,10 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		if infix == nil {
 			return leftExp
 		}
+		// { が構造体リテラルになるのは型名の識別子の直後だけ
+		if _, ok := leftExp.(*ast.Identifier); !ok && p.peekTokenIs(token.LBRACE) {
+			return leftExp
+		}
 		p.nextToken()
 		leftExp = infix(leftExp)
 	}
@@ -185,31 +984,84 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{
+	ident := &ast.Identifier{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+	// x => x * 2
+	if p.peekTokenIs(token.ARROW) && !p.inGuard {
+		p.nextToken()
+		fn := newArrowFunction(ident.Token)
+		fn.Parameters = append(fn.Parameters, ident)
+		return p.parseArrowBody(fn)
+	}
+	return ident
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 	val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return p.parseBigIntLiteral()
+	}
+	if err != nil {
+		p.positionedError(p.curToken, "could not parse %q to integer", p.curToken.Literal)
+		return nil
+	}
+	lit.Value = val
+	return lit
+}
+
+func (p *Parser) parseBigIntLiteral() ast.Expression {
+	val, ok := new(big.Int).SetString(p.curToken.Literal, 0)
+	if !ok {
+		p.positionedError(p.curToken, "could not parse %q to integer", p.curToken.Literal)
+		return nil
+	}
+	return &ast.BigIntLiteral{Token: p.curToken, Value: val}
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	val, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.positionedError(p.curToken, "float literal %q out of range", p.curToken.Literal)
+		return nil
+	}
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q to integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.positionedError(p.curToken, "could not parse %q to float", p.curToken.Literal)
 		return nil
 	}
 	lit.Value = val
 	return lit
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	// (x, y) => x + y
+	// ガード中の (x) => はガードの終わりなのでアロー関数として扱わない
+	if !p.inGuard && p.isArrowParameters() {
+		fn := newArrowFunction(p.curToken)
+		if !p.parseFunctionParameters(fn) {
+			return nil
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		return p.parseArrowBody(fn)
+	}
+	inGuard := p.inGuard
+	p.inGuard = false
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
+	p.inGuard = inGuard
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -232,6 +1084,10 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	exp.Consequence = p.parseBlockStatement()
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
+		if p.peekTokenIs(token.IF) {
+			exp.Alternative = p.parseElseIf()
+			return exp
+		}
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -240,41 +1096,294 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return exp
 }
 
+// else if (...) { ... } はif式1つだけを含むブロックとしてAlternativeに入れる
+func (p *Parser) parseElseIf() *ast.BlockStatement {
+	p.nextToken()
+	s := &ast.ExpressionStatement{Token: p.curToken}
+	s.Expression = p.parseIfExpression()
+	return &ast.BlockStatement{Token: s.Token, Statements: []ast.Statement{s}}
+}
+
+// 三項演算子は右結合 ex: a ? b : c ? d : e は a ? b : (c ? d : e)
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+	p.nextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	exp.Alternative = p.parseExpression(TERNARY - 1)
+	return exp
+}
+
+// match (v) { 0 => "zero", [x, y] if x > y => x, _ => v }
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken, Arms: make([]*ast.MatchArm, 0)}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	p.checkUnreachableArms(exp)
+	if !p.isExhaustive(exp) {
+		p.positionedWarning(exp.Token, "match may not be exhaustive: add a wildcard arm `_ => ...`")
+	}
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil || !p.checkDuplicateBindings(arm.Pattern) {
+		return nil
+	}
+	p.enterScope(patternBindings(arm.Pattern))
+	defer p.leaveScope()
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		p.inGuard = true
+		arm.Guard = p.parseExpression(LOWEST)
+		p.inGuard = false
+	}
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	arm.Body = p.parseExpression(LOWEST)
+	return arm
+}
+
+// 全ての値にマッチするアームより後ろのアームは実行されない
+// 後で宣言されるenumのバリアント名を書いた場合など, 識別子が束縛になっていることに気づけるよう警告する
+func (p *Parser) checkUnreachableArms(exp *ast.MatchExpression) {
+	for i, arm := range exp.Arms {
+		if i == len(exp.Arms)-1 || arm.Guard != nil {
+			continue
+		}
+		switch pattern := arm.Pattern.(type) {
+		case *ast.Identifier:
+			p.positionedWarning(pattern.Token, "match arm %s matches every value: later arms are unreachable", pattern)
+			return
+		case *ast.WildcardPattern:
+			p.positionedWarning(pattern.Token, "match arm _ matches every value: later arms are unreachable")
+			return
+		}
+	}
+}
+
+// ガードのないワイルドカードか識別子のアームがあれば全ての値にマッチする
+// enumのバリアントを全て網羅している場合も同様
+func (p *Parser) isExhaustive(exp *ast.MatchExpression) bool {
+	covered := make(map[string]bool)
+	for _, arm := range exp.Arms {
+		if arm.Guard != nil {
+			continue
+		}
+		switch pattern := arm.Pattern.(type) {
+		case *ast.WildcardPattern, *ast.Identifier:
+			return true
+		case *ast.VariantPattern:
+			// Circle(0) のようにフィールドが絞り込まれていれば網羅したことにならない
+			irrefutable := true
+			for _, f := range pattern.Fields {
+				if _, refutable := refutablePattern(f); refutable != nil {
+					irrefutable = false
+				}
+			}
+			covered[pattern.Name.Value] = covered[pattern.Name.Value] || irrefutable
+		}
+	}
+	for name := range covered {
+		v := p.lookupVariant(name)
+		if v == nil {
+			continue
+		}
+		all := true
+		for _, other := range v.siblings {
+			all = all && covered[other]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	exp := &ast.FunctionLiteral{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	exp.Parameters = p.parseFunctionParameters()
-	if !p.expectPeek(token.LBRACE) {
+	if !p.parseFunctionBody(exp) {
 		return nil
 	}
-	exp.Body = p.parseBlockStatement()
 	return exp
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	params := make([]*ast.Identifier, 0)
+// 引数リストと本体を読む. curTokenは(
+func (p *Parser) parseFunctionBody(fn *ast.FunctionLiteral) bool {
+	if !p.parseFunctionParameters(fn) {
+		return false
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return false
+	}
+	// 関数本体から外側のループをbreak, continueすることはできない
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.funcDepth++
+	p.enterScope(functionBindings(fn))
+	fn.Body = p.parseBlockStatement()
+	p.leaveScope()
+	p.funcDepth--
+	p.loopDepth = loopDepth
+	return true
+}
+
+// curTokenの(に対応する)の直後が=>か先読みして判断する
+// 字句解析器を複製して読み進めるのでパーサーの状態は変わらない
+func (p *Parser) isArrowParameters() bool {
+	l := *p.l
+	depth := 1
+	for tok := p.peekToken; tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth == 0 {
+				return l.NextToken().Type == token.ARROW
+			}
+		}
+	}
+	return false
+}
+
+// アロー関数はFunctionLiteralに脱糖する
+func newArrowFunction(start token.Token) *ast.FunctionLiteral {
+	return &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn", Line: start.Line, Column: start.Column},
+		Parameters: make([]*ast.Identifier, 0),
+	}
+}
+
+// 本体は式文1つだけのブロックになる
+// curTokenは=>
+func (p *Parser) parseArrowBody(fn *ast.FunctionLiteral) ast.Expression {
+	body := &ast.BlockStatement{Token: p.curToken}
+	p.nextToken()
+	s := &ast.ExpressionStatement{Token: p.curToken}
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	p.funcDepth++
+	p.enterScope(functionBindings(fn))
+	s.Expression = p.parseExpression(LOWEST)
+	p.leaveScope()
+	p.funcDepth--
+	p.loopDepth = loopDepth
+	body.Statements = []ast.Statement{s}
+	fn.Body = body
+	return fn
+}
+
+func functionBindings(fn *ast.FunctionLiteral) []*ast.Identifier {
+	if fn.Rest == nil {
+		return fn.Parameters
+	}
+	return append(fn.Parameters[:len(fn.Parameters):len(fn.Parameters)], fn.Rest)
+}
+
+// fn(a, b = 10, ...rest)
+// 重複した名前, 残余引数より後ろの引数, 識別子以外の引数はエラーにする
+func (p *Parser) parseFunctionParameters(fn *ast.FunctionLiteral) bool {
+	fn.Parameters = make([]*ast.Identifier, 0)
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return params
+		return true
 	}
+	seen := make(map[string]bool)
 	p.nextToken()
-	params = append(params, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	if !p.parseFunctionParameter(fn, seen) {
+		return false
+	}
 	for p.peekTokenIs(token.COMMA) {
+		if fn.Rest != nil {
+			p.positionedError(fn.Rest.Token, "rest parameter ...%s must be last", fn.Rest.Value)
+			return false
+		}
 		p.nextToken()
 		p.nextToken()
-		params = append(params, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		if !p.parseFunctionParameter(fn, seen) {
+			return false
+		}
 	}
-	if !p.expectPeek(token.RPAREN) {
-		return nil
+	return p.expectPeek(token.RPAREN)
+}
+
+func (p *Parser) parseFunctionParameter(fn *ast.FunctionLiteral, seen map[string]bool) bool {
+	rest := p.curTokenIs(token.ELLIPSIS)
+	if rest {
+		p.nextToken()
+	}
+	if !p.curTokenIs(token.IDENT) {
+		p.positionedError(p.curToken, "expected parameter name. got %s", p.curToken.Type)
+		return false
+	}
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if seen[ident.Value] {
+		p.positionedError(ident.Token, "duplicate parameter %s", ident.Value)
+		return false
+	}
+	seen[ident.Value] = true
+
+	if rest {
+		if p.peekTokenIs(token.ASSIGN) {
+			p.positionedError(ident.Token, "rest parameter ...%s cannot have a default value", ident.Value)
+			return false
+		}
+		fn.Rest = ident
+		return true
+	}
+	fn.Parameters = append(fn.Parameters, ident)
+	// 位置引数でデフォルト値のある引数を飛ばすことはできない
+	if !p.peekTokenIs(token.ASSIGN) && len(fn.Defaults) > 0 {
+		p.positionedError(ident.Token, "parameter %s without default value follows a parameter with one", ident.Value)
+		return false
+	}
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		if fn.Defaults == nil {
+			fn.Defaults = make(map[string]ast.Expression)
+		}
+		fn.Defaults[ident.Value] = p.parseExpression(LOWEST)
 	}
-	return params
+	return true
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = make([]ast.Statement, 0)
+	p.blockDepth++
 	p.nextToken()
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		s := p.parseStatement()
@@ -283,6 +1392,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	p.blockDepth--
 	return block
 }
 
@@ -311,19 +1421,47 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		p.nextToken()
 		return args
 	}
+	// 括弧の中ではガードの終わりと紛れないのでアロー関数を書ける
+	inGuard := p.inGuard
+	p.inGuard = false
+	keywords := make(map[string]bool)
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	args = append(args, p.parseCallArgument(keywords))
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		args = append(args, p.parseCallArgument(keywords))
 	}
+	p.inGuard = inGuard
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 	return args
 }
 
+// f(x, b: 3) のb: 3はキーワード引数
+// キーワード引数より後ろの位置引数, 同じ名前のキーワード引数はエラーにする
+func (p *Parser) parseCallArgument(keywords map[string]bool) ast.Expression {
+	if !p.curTokenIs(token.IDENT) || !p.peekTokenIs(token.COLON) {
+		if len(keywords) > 0 {
+			p.positionedError(p.curToken, "positional argument after keyword argument")
+		}
+		return p.parseExpression(LOWEST)
+	}
+	arg := &ast.KeywordArgument{
+		Token: p.curToken,
+		Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+	if keywords[arg.Name.Value] {
+		p.positionedError(arg.Token, "duplicate keyword argument %s", arg.Name.Value)
+	}
+	keywords[arg.Name.Value] = true
+	p.nextToken()
+	p.nextToken()
+	arg.Value = p.parseExpression(LOWEST)
+	return arg
+}
+
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	exp := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -336,6 +1474,106 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+// Point{x: 1, y: 2}
+// 宣言済みの構造体であれば存在しないフィールドをエラーにする
+func (p *Parser) parseStructLiteral(name ast.Expression) ast.Expression {
+	// 左辺の解析に失敗していればエラーは報告済み
+	ident, ok := name.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	lit := &ast.StructLiteral{Token: p.curToken, Name: ident, Fields: make([]*ast.StructLiteralField, 0)}
+	known, declared := p.structs[ident.Value]
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.StructLiteralField{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if seen[field.Name.Value] {
+			p.positionedError(field.Name.Token, "duplicate field %s in %s literal", field.Name.Value, ident.Value)
+			return nil
+		}
+		seen[field.Name.Value] = true
+		if declared && !contains(known, field.Name.Value) {
+			p.positionedError(field.Name.Token, "unknown field %s in struct %s", field.Name.Value, ident.Value)
+			return nil
+		}
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		field.Value = p.parseExpression(LOWEST)
+		lit.Fields = append(lit.Fields, field)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return lit
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 代入は右結合 ex: a = b = c は a = (b = c)
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+	ident, ok := target.(*ast.Identifier)
+	if !ok {
+		p.positionedError(p.curToken, "invalid assignment target %s", target)
+		return nil
+	}
+	b, ok := p.lookup(ident.Value)
+	if b.isConst {
+		p.positionedError(ident.Token, "cannot assign to const %s", ident.Value)
+		return nil
+	}
+	if !ok {
+		s := p.scopes[len(p.scopes)-1]
+		s.unresolved = append(s.unresolved, ident)
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// a |> f(b) は f(a, b) に脱糖する
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	p.nextToken()
+	right := p.parseExpression(PIPE)
+	if right == nil {
+		return nil
+	}
+	call, ok := right.(*ast.CallExpression)
+	if !ok {
+		p.positionedError(tok, "right side of |> must be a call expression. got %s", right)
+		return nil
+	}
+	call.Arguments = append([]ast.Expression{left}, call.Arguments...)
+	return call
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -63,6 +63,236 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	return true
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	cases := []struct {
+		input        string
+		wantBindings []string
+		want         string
+	}{
+		{"let [a, b] = xs;", []string{"a", "b"}, "let [a, b] = xs;"},
+		{"let [a, b, ...rest] = xs;", []string{"a", "b", "rest"}, "let [a, b, ...rest] = xs;"},
+		{"let [] = xs;", []string{}, "let [] = xs;"},
+		{"let {name, age: years} = person;", []string{"name", "years"}, "let {name, age: years} = person;"},
+		{"let {pos: [x, y], tags: {first}} = item;", []string{"x", "y", "first"}, "let {pos: [x, y], tags: {first}} = item;"},
+		{"let [{id}, [c, ...d]] = f();", []string{"id", "c", "d"}, "let [{id}, [c, ...d]] = f();"},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+		letS, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("s does not *ast.LetStatement got=%T", program.Statements[0])
+		}
+		if letS.Name != nil {
+			t.Fatalf("letS.Name is not nil. got=%s", letS.Name)
+		}
+		bindings := collectBindings(letS.Pattern)
+		if len(bindings) != len(tt.wantBindings) {
+			t.Fatalf("bindings length is wrong. want=%v got=%v", tt.wantBindings, bindings)
+		}
+		for i, b := range tt.wantBindings {
+			if bindings[i] != b {
+				t.Errorf("bindings[%d] is not %q. got=%q", i, b, bindings[i])
+			}
+		}
+		if letS.String() != tt.want {
+			t.Errorf("letS.String() wrong. want=%q, got=%q", tt.want, letS.String())
+		}
+	}
+}
+
+func collectBindings(pattern ast.Pattern) []string {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return []string{pattern.Value}
+	case *ast.ArrayPattern:
+		names := make([]string, 0)
+		for _, e := range pattern.Elements {
+			names = append(names, collectBindings(e)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest.Value)
+		}
+		return names
+	case *ast.HashPattern:
+		names := make([]string, 0)
+		for _, pair := range pattern.Pairs {
+			names = append(names, collectBindings(pair.Value)...)
+		}
+		return names
+	}
+	return nil
+}
+
+func TestInvalidDestructuringLetStatements(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"let [a, ...b, c] = xs;", "1:12: rest element ...b must be last"},
+		{"let [a, a] = xs;", "1:9: duplicate binding a in pattern"},
+		{"let {a, b: a} = h;", "1:12: duplicate binding a in pattern"},
+		{"let [1] = xs;", "1:6: refutable pattern 1 in let"},
+		{"let {kind: \"a\"} = h;", "1:12: refutable pattern \"a\" in let"},
+		{"let [a, +] = xs;", "1:9: expected pattern. got +"},
+		{"let {1} = h;", "expected next token type to be IDENT, but got INT"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantIdent string
+		wantVal   interface{}
+	}{
+		{"const x = 5;", "x", 5},
+		{"const y = true;", "y", true},
+		{"const foo = y;", "foo", "y"},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+		s, ok := program.Statements[0].(*ast.ConstStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ConstStatement. got=%T", program.Statements[0])
+		}
+		if s.Name.Value != tt.wantIdent {
+			t.Errorf("s.Name.Value is not %q. got=%q", tt.wantIdent, s.Name.Value)
+		}
+		if !testLiteralExpression(t, s.Value, tt.wantVal) {
+			return
+		}
+	}
+}
+
+func TestConstReassignment(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"const x = 1; x = 2;", "1:14: cannot assign to const x"},
+		{"const x = 1; x += 2;", "1:14: cannot assign to const x"},
+		{"const x = 1; if (true) { x = 2; }", "1:26: cannot assign to const x"},
+		{"const x = 1; let f = fn() { x = 2; };", "1:29: cannot assign to const x"},
+		{"const x = 1; let x = 2;", "1:18: cannot redeclare const x"},
+		{"const x = 1; const x = 2;", "1:20: cannot redeclare const x"},
+		{"const x = 1; let [a, x] = xs;", "1:22: cannot redeclare const x"},
+		{"const x = 1; for (x in xs) {}", "1:19: cannot redeclare const x"},
+		// 関数本体の代入は後で宣言されたconstも検査する
+		{"let f = fn() { x = 2 }; const x = 1; f();", "1:16: cannot assign to const x"},
+		{"let f = fn() { let g = fn() { x += 1 }; }; const x = 1;", "1:31: cannot assign to const x"},
+		{"let f = fn() { match (v) { _ => x = 1 } }; const x = 1;", "1:33: cannot assign to const x"},
+		{"let f = fn() { x = 2; let x = 3; }; const x = 1;", ""},
+		{"let f = fn(x) { x = 2 }; const x = 1;", ""},
+		{"let f = fn() { x = 2 }; let x = 1;", ""},
+		// 関数の引数やmatchのパターンで隠された名前には代入できる
+		{"const x = 1; let f = fn(x) { x = 2; };", ""},
+		{"const x = 1; let f = x => x = 2;", ""},
+		{"const x = 1; let f = fn() { let x = 2; x = 3; };", ""},
+		{"const x = 1; match (v) { x => x = 2 };", ""},
+		{"let x = 1; x = 2;", ""},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	input := `import "lib/strings.mk" as s;`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+	s, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	if s.Path.Value != "lib/strings.mk" {
+		t.Errorf("s.Path.Value is not %q. got=%q", "lib/strings.mk", s.Path.Value)
+	}
+	testIdentifier(t, s.Alias, "s")
+	if s.String() != input {
+		t.Errorf("s.String() wrong. want=%q, got=%q", input, s.String())
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantNames []string
+	}{
+		{"export let f = fn(x) { x };", []string{"f"}},
+		{"export let [a, {b}] = xs;", []string{"a", "b"}},
+		{"export const pi = 3;", []string{"pi"}},
+		{"export struct Point { x, y }", []string{"Point"}},
+		{"export enum Shape { Circle(r), Empty }", []string{"Shape", "Circle", "Empty"}},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+		s, ok := program.Statements[0].(*ast.ExportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ExportStatement. got=%T", program.Statements[0])
+		}
+		if len(s.Names) != len(tt.wantNames) {
+			t.Fatalf("s.Names length is wrong. want=%d, got=%d", len(tt.wantNames), len(s.Names))
+		}
+		for i, name := range tt.wantNames {
+			testIdentifier(t, s.Names[i], name)
+		}
+	}
+}
+
+func TestInvalidImportExport(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{`import "a.mk";`, "expected next token type to be AS, but got ;"},
+		{`import a as b;`, "expected next token type to be STRING, but got IDENT"},
+		{`import "" as a;`, "1:8: import path must not be empty"},
+		{`import "a.mk" as a; a = 1;`, "1:21: cannot assign to const a"},
+		{`if (x) { import "a.mk" as a; }`, "1:10: import is only allowed at the top level"},
+		{`let f = fn() { export let x = 1; };`, "1:16: export is only allowed at the top level"},
+		{`export 1;`, "1:8: expected let, const, struct or enum after export. got INT"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	cases := []struct {
 		input   string
@@ -147,6 +377,137 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestPrefixedIntegerLiteralExpression(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantValue int64
+	}{
+		{"0xff", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_dead_BEEF", 0xdeadbeef},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		s := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := s.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("s.Expression is not ast.IntegerLiteral. got=%T", s.Expression)
+		}
+		if literal.Value != tt.wantValue {
+			t.Errorf("literal.Value is not %d. got=%d", tt.wantValue, literal.Value)
+		}
+		// 元の表記を保持する
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() is not %q. got=%q", tt.input, literal.String())
+		}
+	}
+}
+
+func TestBigIntLiteralExpression(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantValue string
+	}{
+		{"9223372036854775808", "9223372036854775808"},
+		{"123_456_789_012_345_678_901", "123456789012345678901"},
+		{"0xffff_ffff_ffff_ffff_ff", "4722366482869645213695"},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		s := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := s.Expression.(*ast.BigIntLiteral)
+		if !ok {
+			t.Fatalf("s.Expression is not ast.BigIntLiteral. got=%T", s.Expression)
+		}
+		if literal.Value.String() != tt.wantValue {
+			t.Errorf("literal.Value is not %s. got=%s", tt.wantValue, literal.Value)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() is not %q. got=%q", tt.input, literal.String())
+		}
+	}
+}
+
+func TestMalformedIntegerLiteralError(t *testing.T) {
+	checkFirstParserError(t, "let x = 0b12;", `1:9: invalid digit '2' in binary literal "0b12"`)
+}
+
+func TestNumberLiteralErrorsArePositioned(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"let x = 09;", `1:9: leading zero in decimal literal "09" (use 0o prefix for octal)`},
+		{"let x = 017;", `1:9: leading zero in decimal literal "017" (use 0o prefix for octal)`},
+		{"let x = 1e400;", `1:9: float literal "1e400" out of range`},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	s := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := s.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("s.Expression is not ast.StringLiteral. got=%T", s.Expression)
+	}
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value is not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantValue float64
+	}{
+		{"0.5;", 0.5},
+		{"1e-3;", 0.001},
+		{"2.5E+2;", 250},
+		{"1_000.25;", 1000.25},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+		s, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+		literal, ok := s.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("s.Expression is not ast.FloatLiteral. got=%T", s.Expression)
+		}
+		if literal.Value != tt.wantValue {
+			t.Fatalf("literal.Value is not %g. got=%g", tt.wantValue, literal.Value)
+		}
+	}
+}
+
 func TestBooleanExpression(t *testing.T) {
 	cases := []struct {
 		input    string
@@ -244,82 +605,756 @@ func TestIfElseExpression(t *testing.T) {
 	if !ok {
 		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", is.Consequence.Statements[0])
 	}
-	if !testIdentifier(t, con.Expression, "x") {
-		return
+	if !testIdentifier(t, con.Expression, "x") {
+		return
+	}
+	if len(is.Alternative.Statements) != 1 {
+		t.Fatalf("alternative is not 1 statement. got=%d", len(is.Alternative.Statements))
+	}
+	alt, ok := is.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", is.Alternative.Statements[0])
+	}
+	if !testIdentifier(t, alt.Expression, "y") {
+		return
+	}
+}
+
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else if (z) { z } else { 0 }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	is := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if !testInfixExpression(t, is.Condition, "x", "<", "y") {
+		return
+	}
+	if len(is.Alternative.Statements) != 1 {
+		t.Fatalf("alternative is not 1 statement. got=%d", len(is.Alternative.Statements))
+	}
+	alt, ok := is.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", is.Alternative.Statements[0])
+	}
+	elseIf, ok := alt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alt.Expression is not ast.IfExpression. got=%T", alt.Expression)
+	}
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
+	}
+	last := elseIf.Alternative.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if !testIdentifier(t, last.Condition, "z") {
+		return
+	}
+	if !testLiteralExpression(t, last.Alternative.Statements[0].(*ast.ExpressionStatement).Expression, 0) {
+		return
+	}
+
+	want := "if(x < y) xelse if(x > y) yelse ifz zelse 0"
+	if program.String() != want {
+		t.Errorf("program.String() wrong. want=%q, got=%q", want, program.String())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (v) {
+		0 => "zero",
+		-1 => "minus one",
+		[x, y] if x > y => x - y,
+		{kind: "a", value} => value,
+		_ => v,
+	}`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(p.Warnings()) != 0 {
+		t.Fatalf("parser has warnings: %q", p.Warnings())
+	}
+	s := program.Statements[0].(*ast.ExpressionStatement)
+	me, ok := s.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("s.Expression is not ast.MatchExpression. got=%T", s.Expression)
+	}
+	if !testIdentifier(t, me.Subject, "v") {
+		return
+	}
+	if len(me.Arms) != 5 {
+		t.Fatalf("me.Arms length is wrong. got=%d", len(me.Arms))
+	}
+
+	lit, ok := me.Arms[0].Pattern.(*ast.LiteralPattern)
+	if !ok {
+		t.Fatalf("Arms[0].Pattern is not ast.LiteralPattern. got=%T", me.Arms[0].Pattern)
+	}
+	if !testLiteralExpression(t, lit.Value, 0) {
+		return
+	}
+	arr, ok := me.Arms[2].Pattern.(*ast.ArrayPattern)
+	if !ok {
+		t.Fatalf("Arms[2].Pattern is not ast.ArrayPattern. got=%T", me.Arms[2].Pattern)
+	}
+	if len(arr.Elements) != 2 {
+		t.Fatalf("arr.Elements length is wrong. got=%d", len(arr.Elements))
+	}
+	if !testInfixExpression(t, me.Arms[2].Guard, "x", ">", "y") {
+		return
+	}
+	if !testInfixExpression(t, me.Arms[2].Body, "x", "-", "y") {
+		return
+	}
+	hash, ok := me.Arms[3].Pattern.(*ast.HashPattern)
+	if !ok {
+		t.Fatalf("Arms[3].Pattern is not ast.HashPattern. got=%T", me.Arms[3].Pattern)
+	}
+	if _, ok := hash.Pairs[0].Value.(*ast.LiteralPattern); !ok {
+		t.Fatalf("hash.Pairs[0].Value is not ast.LiteralPattern. got=%T", hash.Pairs[0].Value)
+	}
+	if _, ok := me.Arms[4].Pattern.(*ast.WildcardPattern); !ok {
+		t.Fatalf("Arms[4].Pattern is not ast.WildcardPattern. got=%T", me.Arms[4].Pattern)
+	}
+
+	want := `match(v) { 0 => "zero", -1 => "minus one", [x, y] if (x > y) => (x - y), {kind: "a", value} => value, _ => v }`
+	if me.String() != want {
+		t.Errorf("me.String() wrong. want=%q, got=%q", want, me.String())
+	}
+
+	// 出力を再び構文解析しても同じ結果になる
+	p = parser.New(lexer.New(want))
+	reparsed := p.ParseProgram()
+	checkParserErrors(t, p)
+	if reparsed.String() != want {
+		t.Errorf("reparsed.String() wrong. want=%q, got=%q", want, reparsed.String())
+	}
+}
+
+func TestMatchGuardWithParentheses(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"match (v) { n if (n > 0) => 1, _ => 0 }", "match(v) { n if (n > 0) => 1, _ => 0 }"},
+		{"match (v) { n if !(n) => 1, _ => 0 }", "match(v) { n if (!n) => 1, _ => 0 }"},
+		{"match (v) { n if (n) => 1, _ => 0 }", "match(v) { n if n => 1, _ => 0 }"},
+		{"match (v) { n if f((x) => x) => 1, _ => 0 }", "match(v) { n if f(fn(x) x) => 1, _ => 0 }"},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		got := program.String()
+		if got != tt.want {
+			t.Errorf("want=%q, got=%q", tt.want, got)
+		}
+	}
+}
+
+func TestEnumStatement(t *testing.T) {
+	input := "enum Shape { Circle(r), Rect(w, h), Empty }"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+	s, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+	if s.Name.Value != "Shape" {
+		t.Errorf("s.Name.Value is not %q. got=%q", "Shape", s.Name.Value)
+	}
+	wantVariants := []struct {
+		name   string
+		fields []string
+	}{
+		{"Circle", []string{"r"}},
+		{"Rect", []string{"w", "h"}},
+		{"Empty", []string{}},
+	}
+	if len(s.Variants) != len(wantVariants) {
+		t.Fatalf("s.Variants length is wrong. want=%d, got=%d", len(wantVariants), len(s.Variants))
+	}
+	for i, want := range wantVariants {
+		v := s.Variants[i]
+		if v.Name.Value != want.name {
+			t.Errorf("Variants[%d].Name is not %q. got=%q", i, want.name, v.Name.Value)
+		}
+		if len(v.Fields) != len(want.fields) {
+			t.Fatalf("Variants[%d].Fields length is wrong. want=%d, got=%d", i, len(want.fields), len(v.Fields))
+		}
+		for j, f := range want.fields {
+			testIdentifier(t, v.Fields[j], f)
+		}
+	}
+	if s.String() != input {
+		t.Errorf("s.String() wrong. want=%q, got=%q", input, s.String())
+	}
+}
+
+func TestVariantPatterns(t *testing.T) {
+	input := `enum Shape { Circle(r), Rect(w, h), Empty };
+	match (s) {
+		Circle(r) => r,
+		Rect(w, _) => w,
+		Empty => 0,
+	}`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(p.Warnings()) != 0 {
+		t.Fatalf("parser has warnings: %q", p.Warnings())
+	}
+	me := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	wants := []string{"Circle(r)", "Rect(w, _)", "Empty"}
+	for i, want := range wants {
+		vp, ok := me.Arms[i].Pattern.(*ast.VariantPattern)
+		if !ok {
+			t.Fatalf("Arms[%d].Pattern is not ast.VariantPattern. got=%T", i, me.Arms[i].Pattern)
+		}
+		if vp.String() != want {
+			t.Errorf("Arms[%d].Pattern wrong. want=%q, got=%q", i, want, vp.String())
+		}
+	}
+}
+
+func TestFunctionLocalEnum(t *testing.T) {
+	input := "let f = fn() { enum T { A, B } }; let [A] = xs; match (v) { [A] => 1, _ => 2 }"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	me := program.Statements[2].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	arr := me.Arms[0].Pattern.(*ast.ArrayPattern)
+	if _, ok := arr.Elements[0].(*ast.Identifier); !ok {
+		t.Errorf("A outside f is not a binding. got=%T", arr.Elements[0])
+	}
+}
+
+func TestInvalidEnums(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"enum S { A, A }", "1:13: duplicate variant A in enum S"},
+		{"enum S { A(x, x) }", "1:15: duplicate field x in variant A"},
+		{"enum S { A(1) }", "expected next token type to be IDENT, but got INT"},
+		{"enum S { A(x) }; match (s) { A(x, y) => x, _ => 0 }", "1:30: variant A has 1 field(s), but pattern has 2"},
+		{"enum S { A(x) }; match (s) { A => 0, _ => 0 }", "1:30: variant A has 1 field(s), but pattern has 0"},
+		{"enum S { A(x) }; let [A(x)] = xs;", "1:23: refutable pattern A(x) in let"},
+		{"enum S { A }; let [A] = xs;", "1:20: refutable pattern A in let"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestNegativeLiteralPatterns(t *testing.T) {
+	input := "match (v) { -1 => 1, -1.5 => 2, -0x10 => 3, _ => 4 }"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	want := "match(v) { -1 => 1, -1.5 => 2, -0x10 => 3, _ => 4 }"
+	if program.String() != want {
+		t.Errorf("want=%q, got=%q", want, program.String())
+	}
+}
+
+func TestInvalidNegativeLiteralPatterns(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"match (v) { n if n > 0 => 1, -x => 2 }", "1:31: expected number after - in pattern. got IDENT"},
+		{`match (v) { -"a" => 1, _ => 2 }`, "1:14: expected number after - in pattern. got STRING"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestMatchExhaustivenessWarning(t *testing.T) {
+	cases := []struct {
+		input       string
+		wantWarning string
+	}{
+		{`match (v) { 0 => 1, 1 => 2 }`, "1:1: match may not be exhaustive: add a wildcard arm `_ => ...`"},
+		{`let a = match (v) { x if x > 0 => 1 }`, "1:9: match may not be exhaustive: add a wildcard arm `_ => ...`"},
+		{`match (v) { 0 => 1, _ => 2 }`, ""},
+		{`match (v) { 0 => 1, other => other }`, ""},
+		{`match (xs) { [x] if any(xs, y => y > x) => 1, _ => (z => z) }`, ""},
+		{`enum S { A(x), B }; match (s) { A(x) => x, B => 0 }`, ""},
+		{`enum S { A(x), B }; match (s) { A(x) => x }`, "1:21: match may not be exhaustive: add a wildcard arm `_ => ...`"},
+		{`enum S { A(x), B }; match (s) { A(0) => 0, B => 0 }`, "1:21: match may not be exhaustive: add a wildcard arm `_ => ...`"},
+		{`enum S { A(x), B }; match (s) { A(x) if x > 0 => x, B => 0 }`, "1:21: match may not be exhaustive: add a wildcard arm `_ => ...`"},
+		{`match (v) {}`, "1:1: match may not be exhaustive: add a wildcard arm `_ => ...`"},
+		{`match (v) { _ => 1, 0 => 2 }`, "1:13: match arm _ matches every value: later arms are unreachable"},
+		{`match (v) { x if x > 0 => 1, x => 2 }`, ""},
+		// 後で宣言されたバリアント名は束縛になる
+		{`let area = fn(s) { match (s) { Empty => 0, Full(x) => x } }; enum S { Empty, Full(x) }`,
+			"1:32: match arm Empty matches every value: later arms are unreachable"},
+		{`enum S { Empty, Full(x) }; let area = fn(s) { match (s) { Empty => 0, Full(x) => x } }`, ""},
+		// 関数内で宣言されたバリアントは関数の外では束縛になる
+		{`let f = fn() { enum T { A, B } }; match (v) { A => 1, B => 2 }`,
+			"1:47: match arm A matches every value: later arms are unreachable"},
+		{`let f = fn() { enum T { A, B }; match (v) { A => 1, B => 2 } }`, ""},
+		// 引数で隠されたバリアント名は束縛になる
+		{`enum T { A, B }; let f = fn(A) { match (v) { A => 1, B => 2 } }`,
+			"1:46: match arm A matches every value: later arms are unreachable"},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if tt.wantWarning == "" {
+			if len(p.Warnings()) != 0 {
+				t.Errorf("parser has warnings: %q", p.Warnings())
+			}
+			continue
+		}
+		if len(p.Warnings()) != 1 || p.Warnings()[0] != tt.wantWarning {
+			t.Errorf("warnings wrong. want=%q, got=%q", tt.wantWarning, p.Warnings())
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	input := `throw "boom";`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+	s, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+	if s.String() != input {
+		t.Errorf("s.String() wrong. want=%q, got=%q", input, s.String())
+	}
+}
+
+func TestTryStatement(t *testing.T) {
+	cases := []struct {
+		input       string
+		wantParam   string
+		wantCatch   bool
+		wantFinally bool
+		want        string
+	}{
+		{"try { f() } catch (e) { g(e) } finally { h() }", "e", true, true, "try f() catch(e) g(e) finally h()"},
+		{"try { f() } catch (err) { err }", "err", true, false, "try f() catch(err) err"},
+		{"try { f() } finally { h() }", "", false, true, "try f() finally h()"},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+		s, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.TryStatement. got=%T", program.Statements[0])
+		}
+		if (s.Catch != nil) != tt.wantCatch {
+			t.Errorf("s.Catch wrong. want catch=%t, got=%v", tt.wantCatch, s.Catch)
+		}
+		if (s.Finally != nil) != tt.wantFinally {
+			t.Errorf("s.Finally wrong. want finally=%t, got=%v", tt.wantFinally, s.Finally)
+		}
+		if tt.wantCatch {
+			testIdentifier(t, s.Param, tt.wantParam)
+		}
+		if s.String() != tt.want {
+			t.Errorf("s.String() wrong. want=%q, got=%q", tt.want, s.String())
+		}
+	}
+}
+
+func TestInvalidTryStatement(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"try { f() }", "1:1: try without catch or finally"},
+		{"try { f() } catch { g() }", "expected next token type to be (, but got {"},
+		{"try { f() } catch (1) { g() }", "expected next token type to be IDENT, but got INT"},
+		{"try f()", "expected next token type to be {, but got IDENT"},
+		// catchの引数はconstを隠すので代入できる
+		{"const e = 1; try { f() } catch (e) { e = 2 } e = 3", "1:46: cannot assign to const e"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestDeferStatement(t *testing.T) {
+	input := `fn(path) { let f = open(path); defer close(f); defer log("done"); read(f) }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(fn.Body.Statements) != 4 {
+		t.Fatalf("fn.Body.Statements does not contain 4 statements. got=%d", len(fn.Body.Statements))
+	}
+	wants := []string{"defer close(f);", `defer log("done");`}
+	for i, want := range wants {
+		s, ok := fn.Body.Statements[i+1].(*ast.DeferStatement)
+		if !ok {
+			t.Fatalf("fn.Body.Statements[%d] is not *ast.DeferStatement. got=%T", i+1, fn.Body.Statements[i+1])
+		}
+		if s.String() != want {
+			t.Errorf("s.String() wrong. want=%q, got=%q", want, s.String())
+		}
+	}
+}
+
+func TestDeferOutsideFunction(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"defer close(f);", "1:1: defer outside function"},
+		{"if (x) { defer close(f); }", "1:10: defer outside function"},
+		{"let f = fn() { 1 }; defer f();", "1:21: defer outside function"},
+		{"fn() { while (x) { defer f(); } }", ""},
+		{"x => if (x) { defer f() }", ""},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x += 1; if (x == 5) { break; } continue }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	ws, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+	if !testInfixExpression(t, ws.Condition, "x", "<", "y") {
+		return
+	}
+	if len(ws.Body.Statements) != 3 {
+		t.Fatalf("body is not 3 statements. got=%d", len(ws.Body.Statements))
+	}
+	is := ws.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if _, ok := is.Consequence.Statements[0].(*ast.BreakStatement); !ok {
+		t.Fatalf("Statements[0] is not ast.BreakStatement. got=%T", is.Consequence.Statements[0])
+	}
+	if _, ok := ws.Body.Statements[2].(*ast.ContinueStatement); !ok {
+		t.Fatalf("Statements[2] is not ast.ContinueStatement. got=%T", ws.Body.Statements[2])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (x in xs) { sum += x; }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	fs, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T", program.Statements[0])
+	}
+	if !testIdentifier(t, fs.Variable, "x") {
+		return
+	}
+	if !testIdentifier(t, fs.Iterable, "xs") {
+		return
+	}
+	if len(fs.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statement. got=%d", len(fs.Body.Statements))
+	}
+	if fs.String() != "for(x in xs) (sum += x)" {
+		t.Fatalf("fs.String() wrong. got=%q", fs.String())
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"if (x) { continue; }", "1:10: continue outside loop"},
+		{"while (x) { let f = fn() { break; }; }", "1:28: break outside loop"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	}
+	s, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	fl, ok := s.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("s.Expression is not ast.FunctionLiteral. got=%T", s.Expression)
+	}
+	if len(fl.Parameters) != 2 {
+		t.Fatalf("parameters length is wrong. got=%d", len(fl.Parameters))
+	}
+	if !testLiteralExpression(t, fl.Parameters[0], "x") {
+		return
+	}
+	if !testLiteralExpression(t, fl.Parameters[1], "y") {
+		return
+	}
+	if len(fl.Body.Statements) != 1 {
+		t.Fatalf("Body.Statements length is wrong. got=%d", len(fl.Body.Statements))
+	}
+	body, ok := fl.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", fl.Body.Statements[0])
+	}
+	testInfixExpression(t, body.Expression, "x", "+", "y")
+}
+
+func TestArrowFunctionParsing(t *testing.T) {
+	cases := []struct {
+		input      string
+		wantParams []string
+		want       string
+	}{
+		{"x => x * 2", []string{"x"}, "fn(x) (x * 2)"},
+		{"(x) => x * 2", []string{"x"}, "fn(x) (x * 2)"},
+		{"(x, y) => x + y", []string{"x", "y"}, "fn(x, y) (x + y)"},
+		{"() => 1", []string{}, "fn() 1"},
+		{"(x) => (y) => x + y", []string{"x"}, "fn(x) fn(y) (x + y)"},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		s := program.Statements[0].(*ast.ExpressionStatement)
+		fn, ok := s.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("s.Expression is not ast.FunctionLiteral. got=%T", s.Expression)
+		}
+		if len(fn.Parameters) != len(tt.wantParams) {
+			t.Fatalf("parameter length is wrong. want=%d got=%d", len(tt.wantParams), len(fn.Parameters))
+		}
+		for i, p := range tt.wantParams {
+			testLiteralExpression(t, fn.Parameters[i], p)
+		}
+		if len(fn.Body.Statements) != 1 {
+			t.Fatalf("Body.Statements length is wrong. got=%d", len(fn.Body.Statements))
+		}
+		if _, ok := fn.Body.Statements[0].(*ast.ExpressionStatement); !ok {
+			t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", fn.Body.Statements[0])
+		}
+		if fn.String() != tt.want {
+			t.Errorf("fn.String() wrong. want=%q, got=%q", tt.want, fn.String())
+		}
+	}
+}
+
+func TestFunctionParametersParsing(t *testing.T) {
+	cases := []struct {
+		input      string
+		wantParams []string
+	}{
+		{"fn() {};", []string{}},
+		{"fn(x) { x };", []string{"x"}},
+		{"fn(x, y) { x + y };", []string{"x", "y"}},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		s := program.Statements[0].(*ast.ExpressionStatement)
+		fn := s.Expression.(*ast.FunctionLiteral)
+		if len(fn.Parameters) != len(tt.wantParams) {
+			t.Fatalf("parameter length is wrong. want=%d got=%d", len(tt.wantParams), len(fn.Parameters))
+		}
+		for i, p := range tt.wantParams {
+			testLiteralExpression(t, fn.Parameters[i], p)
+		}
+	}
+}
+
+func TestFunctionDefaultAndRestParameters(t *testing.T) {
+	cases := []struct {
+		input        string
+		wantParams   []string
+		wantDefaults map[string]interface{}
+		wantRest     string
+		want         string
+	}{
+		{"fn(a, b = 10) {}", []string{"a", "b"}, map[string]interface{}{"b": 10}, "", "fn(a, b = 10) "},
+		{"fn(a, ...rest) {}", []string{"a"}, map[string]interface{}{}, "rest", "fn(a, ...rest) "},
+		{"fn(...rest) {}", []string{}, map[string]interface{}{}, "rest", "fn(...rest) "},
+		{"fn(a = x, b = true, ...c) {}", []string{"a", "b"}, map[string]interface{}{"a": "x", "b": true}, "c", "fn(a = x, b = true, ...c) "},
+		{"(a, b = 1) => a + b", []string{"a", "b"}, map[string]interface{}{"b": 1}, "", "fn(a, b = 1) (a + b)"},
 	}
-	if len(is.Alternative.Statements) != 1 {
-		t.Fatalf("alternative is not 1 statement. got=%d", len(is.Alternative.Statements))
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if len(fn.Parameters) != len(tt.wantParams) {
+			t.Fatalf("parameter length is wrong. want=%d got=%d", len(tt.wantParams), len(fn.Parameters))
+		}
+		for i, p := range tt.wantParams {
+			testLiteralExpression(t, fn.Parameters[i], p)
+		}
+		if len(fn.Defaults) != len(tt.wantDefaults) {
+			t.Fatalf("defaults length is wrong. want=%d got=%d", len(tt.wantDefaults), len(fn.Defaults))
+		}
+		for name, want := range tt.wantDefaults {
+			testLiteralExpression(t, fn.Defaults[name], want)
+		}
+		if tt.wantRest == "" && fn.Rest != nil {
+			t.Fatalf("fn.Rest is not nil. got=%s", fn.Rest)
+		}
+		if tt.wantRest != "" && !testIdentifier(t, fn.Rest, tt.wantRest) {
+			return
+		}
+		if fn.String() != tt.want {
+			t.Errorf("fn.String() wrong. want=%q, got=%q", tt.want, fn.String())
+		}
 	}
-	alt, ok := is.Alternative.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", is.Alternative.Statements[0])
+}
+
+func TestInvalidFunctionParameters(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"fn(a, a) {}", "1:7: duplicate parameter a"},
+		{"fn(a, b = 1, ...a) {}", "1:17: duplicate parameter a"},
+		{"fn(...rest, b = 1) {}", "1:7: rest parameter ...rest must be last"},
+		{"fn(...rest = 1) {}", "1:7: rest parameter ...rest cannot have a default value"},
+		{"fn(1) {}", "1:4: expected parameter name. got INT"},
+		{"(a, 2) => a", "1:5: expected parameter name. got INT"},
+		{"fn(a = 1, b) {}", "1:11: parameter b without default value follows a parameter with one"},
+		{"(a, b = 1, c, ...d) => a", "1:12: parameter c without default value follows a parameter with one"},
 	}
-	if !testIdentifier(t, alt.Expression, "y") {
-		return
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y; }`
+func TestKeywordArguments(t *testing.T) {
+	input := "f(x, b: 3, c: y + 1);"
 
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
-	if len(program.Statements) != 1 {
-		t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
 	}
-	s, ok := program.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	if !testIdentifier(t, exp.Arguments[0], "x") {
+		return
 	}
-	fl, ok := s.Expression.(*ast.FunctionLiteral)
+	kw, ok := exp.Arguments[1].(*ast.KeywordArgument)
 	if !ok {
-		t.Fatalf("s.Expression is not ast.FunctionLiteral. got=%T", s.Expression)
-	}
-	if len(fl.Parameters) != 2 {
-		t.Fatalf("parameters length is wrong. got=%d", len(fl.Parameters))
+		t.Fatalf("exp.Arguments[1] is not ast.KeywordArgument. got=%T", exp.Arguments[1])
 	}
-	if !testLiteralExpression(t, fl.Parameters[0], "x") {
+	if !testIdentifier(t, kw.Name, "b") || !testLiteralExpression(t, kw.Value, 3) {
 		return
 	}
-	if !testLiteralExpression(t, fl.Parameters[1], "y") {
-		return
+	kw, ok = exp.Arguments[2].(*ast.KeywordArgument)
+	if !ok {
+		t.Fatalf("exp.Arguments[2] is not ast.KeywordArgument. got=%T", exp.Arguments[2])
 	}
-	if len(fl.Body.Statements) != 1 {
-		t.Fatalf("Body.Statements length is wrong. got=%d", len(fl.Body.Statements))
+	if !testIdentifier(t, kw.Name, "c") || !testInfixExpression(t, kw.Value, "y", "+", 1) {
+		return
 	}
-	body, ok := fl.Body.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", fl.Body.Statements[0])
+	if exp.String() != "f(x, b: 3, c: (y + 1))" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
 	}
-	testInfixExpression(t, body.Expression, "x", "+", "y")
 }
 
-func TestFunctionParametersParsing(t *testing.T) {
+func TestInvalidKeywordArguments(t *testing.T) {
 	cases := []struct {
-		input      string
-		wantParams []string
+		input     string
+		wantError string
 	}{
-		{"fn() {};", []string{}},
-		{"fn(x) { x };", []string{"x"}},
-		{"fn(x, y) { x + y };", []string{"x", "y"}},
+		{"f(a: 1, 2)", "1:9: positional argument after keyword argument"},
+		{"f(a: 1, a: 2)", "1:9: duplicate keyword argument a"},
 	}
-	for _, tt := range cases {
-		l := lexer.New(tt.input)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		checkParserErrors(t, p)
 
-		s := program.Statements[0].(*ast.ExpressionStatement)
-		fn := s.Expression.(*ast.FunctionLiteral)
-		if len(fn.Parameters) != len(tt.wantParams) {
-			t.Fatalf("parameter length is wrong. want=%d got=%d", len(tt.wantParams), len(fn.Parameters))
-		}
-		for i, p := range tt.wantParams {
-			testLiteralExpression(t, fn.Parameters[i], p)
-		}
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
 	}
 }
 
@@ -374,11 +1409,16 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 / 5;", 5, "/", 5},
 		{"5 > 5;", 5, ">", 5},
 		{"5 < 5;", 5, "<", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 % 5;", 5, "%", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
 		{"true == true;", true, "==", true},
 		{"true != false;", true, "!=", false},
 		{"false == false;", false, "==", false},
+		{"true && false;", true, "&&", false},
+		{"false || true;", false, "||", true},
 	}
 
 	for _, tt := range cases {
@@ -429,6 +1469,41 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
 		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
 		{"add(a + b + c * d / e + f)", "add((((a + b) + ((c * d) / e)) + f))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a == b && c != d", "((a == b) && (c != d))"},
+		{"a < b || !c", "((a < b) || (!c))"},
+		{"a + b % c * d", "(a + ((b % c) * d))"},
+		{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+		{"a = b = c", "(a = (b = c))"},
+		{"a += b * c", "(a += (b * c))"},
+		{"a = b || c", "(a = (b || c))"},
+		{"add(x = 1, y -= 2)", "add((x = 1), (y -= 2))"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a ? b ? c : d : e", "(a ? (b ? c : d) : e)"},
+		{"x = a < b ? a + 1 : b", "(x = ((a < b) ? (a + 1) : b))"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a ?? b || c", "(a ?? (b || c))"},
+		{"a ?? b ? c : d", "((a ?? b) ? c : d)"},
+		{"xs |> filter(f) |> map(g) |> sum()", "sum(map(filter(xs, f), g))"},
+		{"a + b |> f(c * d)", "f((a + b), (c * d))"},
+		{"x = xs |> f()", "(x = f(xs))"},
+		{"a ? b : c |> f()", "f((a ? b : c))"},
+		{"xs |> map(x => x * 2)", "map(xs, fn(x) (x * 2))"},
+		{"xs |> sort(by: key)", "sort(xs, by: key)"},
+		{"f(a ? b : c, d: e)", "f((a ? b : c), d: e)"},
+		{"f = (a, b) => a + b", "(f = fn(a, b) (a + b))"},
+		{"(a + b) * (c)", "((a + b) * c)"},
+		{"((a))", "a"},
+		{"p.x + p.y * 2", "((p.x) + ((p.y) * 2))"},
+		{"-p.x", "(-(p.x))"},
+		{"a.b.c", "((a.b).c)"},
+		{"p.f(1)", "(p.f)(1)"},
+		{"Point(1, 2).x", "(Point(1, 2).x)"},
+		{"Point{x: 1 + 2}.x", "(Point{x: (1 + 2)}.x)"},
+		{`"abc".upper()`, `("abc".upper)()`},
+		{"xs.map(f).keys()", "((xs.map)(f).keys)()"},
+		{"xs |> h.get(k)", "(h.get)(xs, k)"},
 	}
 
 	for _, tt := range cases {
@@ -480,6 +1555,267 @@ func TestCallExpressionParsing(t *testing.T) {
 	}
 }
 
+func TestPipeExpressionParsing(t *testing.T) {
+	input := "xs |> map(f, 1);"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	s := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := s.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("s.Expression is not ast.CallExpression. got=%T", s.Expression)
+	}
+	if !testIdentifier(t, exp.Function, "map") {
+		return
+	}
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+	if !testIdentifier(t, exp.Arguments[0], "xs") {
+		return
+	}
+	if !testIdentifier(t, exp.Arguments[1], "f") {
+		return
+	}
+	if !testLiteralExpression(t, exp.Arguments[2], 1) {
+		return
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	input := "struct Point { x, y };"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+	s, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+	if s.Name.Value != "Point" {
+		t.Errorf("s.Name.Value is not %q. got=%q", "Point", s.Name.Value)
+	}
+	if len(s.Fields) != 2 {
+		t.Fatalf("s.Fields does not contain 2 fields. got=%d", len(s.Fields))
+	}
+	testIdentifier(t, s.Fields[0], "x")
+	testIdentifier(t, s.Fields[1], "y")
+}
+
+func TestStructMethods(t *testing.T) {
+	input := `struct Point {
+		x, y,
+		fn norm(self) { self.x * self.x + self.y * self.y }
+		fn scale(self, k = 2) { Point(self.x * k, self.y * k) },
+	}`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	s, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+	if len(s.Fields) != 2 {
+		t.Fatalf("s.Fields does not contain 2 fields. got=%d", len(s.Fields))
+	}
+	if len(s.Methods) != 2 {
+		t.Fatalf("s.Methods does not contain 2 methods. got=%d", len(s.Methods))
+	}
+	want := "struct Point { x, y, fn norm(self) (((self.x) * (self.x)) + ((self.y) * (self.y))), " +
+		"fn scale(self, k = 2) Point(((self.x) * k), ((self.y) * k)) }"
+	if s.String() != want {
+		t.Errorf("s.String() is not %q. got=%q", want, s.String())
+	}
+}
+
+func TestStructLiteralParsing(t *testing.T) {
+	input := "Point{x: 1, y: 2 * 3}"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	s, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	lit, ok := s.Expression.(*ast.StructLiteral)
+	if !ok {
+		t.Fatalf("s.Expression is not *ast.StructLiteral. got=%T", s.Expression)
+	}
+	if !testIdentifier(t, lit.Name, "Point") {
+		return
+	}
+	if len(lit.Fields) != 2 {
+		t.Fatalf("lit.Fields does not contain 2 fields. got=%d", len(lit.Fields))
+	}
+	testIdentifier(t, lit.Fields[0].Name, "x")
+	testLiteralExpression(t, lit.Fields[0].Value, 1)
+	testIdentifier(t, lit.Fields[1].Name, "y")
+	testInfixExpression(t, lit.Fields[1].Value, 2, "*", 3)
+}
+
+func TestMemberExpressionParsing(t *testing.T) {
+	input := "p.x"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	s, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	exp, ok := s.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("s.Expression is not *ast.MemberExpression. got=%T", s.Expression)
+	}
+	if !testIdentifier(t, exp.Object, "p") {
+		return
+	}
+	testIdentifier(t, exp.Property, "x")
+}
+
+func TestInvalidStructs(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"struct Point { x, x }", "1:19: duplicate field x in struct Point"},
+		{"struct Point { x, 1 }", "expected next token type to be IDENT, but got INT"},
+		{"struct Point { x, fn x(self) {} }", "1:22: duplicate method x in struct Point"},
+		{"struct Point { fn f(self) {} fn f(self) {} }", "1:33: duplicate method f in struct Point"},
+		{"struct Point { fn norm() {} }", "1:19: method norm must take the receiver as its first parameter"},
+		{"struct Point { fn (self) {} }", "expected next token type to be IDENT, but got ("},
+		{"struct Point { x, y }; Point{x: 1, z: 2}", "1:36: unknown field z in struct Point"},
+		{"Point{x: 1, x: 2}", "1:13: duplicate field x in Point literal"},
+		{"Point{x 1}", "expected next token type to be :, but got INT"},
+		{"f(){x: 1}", "no prefix parse function for { found"},
+		{"if (x) { 1 } { 2 }", "no prefix parse function for { found"},
+		{"fn(a,) {}", "1:6: expected parameter name. got )"},
+		{"p.1", "expected next token type to be IDENT, but got INT"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestInvalidPipeTarget(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"xs |> f", "1:4: right side of |> must be a call expression. got f"},
+		{"xs |> 1 + 2", "1:4: right side of |> must be a call expression. got (1 + 2)"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
+func TestConditionalExpressionParsing(t *testing.T) {
+	input := "x < y ? x : y;"
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+	}
+	s, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	exp, ok := s.Expression.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("s.Expression is not ast.ConditionalExpression. got=%T", s.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+	if !testIdentifier(t, exp.Consequence, "x") {
+		return
+	}
+	if !testIdentifier(t, exp.Alternative, "y") {
+		return
+	}
+}
+
+func TestAssignExpressionParsing(t *testing.T) {
+	cases := []struct {
+		input      string
+		wantTarget string
+		wantOp     string
+		wantVal    interface{}
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"x += 1;", "x", "+=", 1},
+		{"x -= y;", "x", "-=", "y"},
+		{"x *= 2;", "x", "*=", 2},
+		{"x /= true;", "x", "/=", true},
+	}
+
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+		s, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+		exp, ok := s.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("s.Expression is not ast.AssignExpression. got=%T", s.Expression)
+		}
+		if !testIdentifier(t, exp.Target, tt.wantTarget) {
+			return
+		}
+		if exp.Operator != tt.wantOp {
+			t.Fatalf("exp.Operator is not %q. got=%q", tt.wantOp, exp.Operator)
+		}
+		if !testLiteralExpression(t, exp.Value, tt.wantVal) {
+			return
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	cases := []struct {
+		input     string
+		wantError string
+	}{
+		{"1 = 2", "1:3: invalid assignment target 1"},
+		{"a + b = c", "1:7: invalid assignment target (a + b)"},
+		{"f() += 1", "1:5: invalid assignment target f()"},
+	}
+
+	for _, tt := range cases {
+		checkFirstParserError(t, tt.input, tt.wantError)
+	}
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, wantVal int64) bool {
 	i, ok := il.(*ast.IntegerLiteral)
 	if !ok {
@@ -569,6 +1905,22 @@ func testInfixExpression(t *testing.T, exp ast.Expression, left interface{}, op
 	return true
 }
 
+// inputを構文解析した最初のエラーがwantか検査する. wantが空であればエラーがないことを検査する
+func checkFirstParserError(t *testing.T, input string, want string) {
+	t.Helper()
+	l := lexer.New(input)
+	p := parser.New(l)
+	p.ParseProgram()
+
+	if want == "" {
+		checkParserErrors(t, p)
+		return
+	}
+	if len(p.Errors()) == 0 || p.Errors()[0] != want {
+		t.Errorf("first error is not %q. got=%q", want, p.Errors())
+	}
+}
+
 func checkParserErrors(t *testing.T, p *parser.Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/kiki-ki/go-monkey/lexer"
+	"github.com/kiki-ki/go-monkey/optimizer"
 	"github.com/kiki-ki/go-monkey/parser"
 )
 
@@ -15,7 +16,7 @@ const (
 	QUIT   = "q"
 )
 
-func Start(in io.Reader, out io.Writer) {
+func Start(in io.Reader, out io.Writer, optimize bool) {
 	scanner := bufio.NewScanner(in)
 
 	for {
@@ -40,6 +41,10 @@ func Start(in io.Reader, out io.Writer) {
 			printParseErrors(out, p.Errors())
 			continue
 		}
+		printParseWarnings(out, p.Warnings())
+		if optimize {
+			program = optimizer.Optimize(program)
+		}
 		io.WriteString(out, program.String())
 		io.WriteString(out, "\n")
 	}
@@ -50,3 +55,9 @@ func printParseErrors(out io.Writer, errors []string) {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
 }
+
+func printParseWarnings(out io.Writer, warnings []string) {
+	for _, msg := range warnings {
+		io.WriteString(out, "\twarning: "+msg+"\n")
+	}
+}